@@ -1,5 +1,6 @@
 package migration
 
+// Change maps the ID of a row in the source pipeline to the ID of the row created for it in the target pipeline.
 type Change struct {
 	FromID int64
 	ToID   int64
@@ -12,3 +13,12 @@ type Changes struct {
 	BuildLogs     int `json:"buildLogs,omitempty"`
 	BuildVersions int `json:"buildVersions,omitempty"`
 }
+
+// Add other changes to these changes.
+func (c *Changes) Add(other Changes) {
+	c.Releases += other.Releases
+	c.ReleaseLogs += other.ReleaseLogs
+	c.Builds += other.Builds
+	c.BuildLogs += other.BuildLogs
+	c.BuildVersions += other.BuildVersions
+}
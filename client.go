@@ -7,7 +7,6 @@ import (
 	"fmt"
 	contracts "github.com/estafette/estafette-ci-contracts"
 	"net/http"
-	"sort"
 	"strings"
 	"time"
 
@@ -20,6 +19,8 @@ const (
 	tokenTimeout = 175 * time.Minute
 	migrationAPI = "/api/migrations"
 	pipelinesAPI = "/api/pipelines"
+	// pageSize of paged list requests
+	pageSize = 100
 )
 
 var (
@@ -35,11 +36,39 @@ type Client interface {
 	GetMigrationByID(taskID string) (*Task, error)
 	// RollbackMigration task in estafette.
 	RollbackMigration(taskID string) (*Changes, error)
+	// RollbackMigrationChanges removes the rows of the entity type with the given IDs, created in the target pipeline by
+	// the migration task, and returns the removed rows, see Rollback.
+	RollbackMigrationChanges(taskID string, entity EntityType, toIDs []int64) (*Changes, error)
 	// GetMigrations returns all migration tasks
 	GetMigrations() ([]*Task, error)
+	// GetMigrationHistory returns the stage attempts of migration task using task ID
+	GetMigrationHistory(taskID string) ([]HistoryEntry, error)
+	// GetMigrationChanges returns the IDs of rows of the entity type created in the target pipeline by the migration task,
+	// mapped from the IDs of the source rows, see Ledger.
+	GetMigrationChanges(taskID string, entity EntityType) ([]Change, error)
 	// GetMigrationByFromRepo of migration task using task ID
 	GetMigrationByFromRepo(source, owner, name string) (*Task, error)
-	GetPipelineBuildStatus(source, owner, name, branch, revisionID string) (string, error)
+	// GetPipelineBuildStatus returns the status of the build of revisionID or, if empty, of the latest build of the branch.
+	// It returns contracts.StatusUnknown if there is no such build.
+	GetPipelineBuildStatus(source, owner, name, branch, revisionID string) (contracts.Status, error)
+	// ListPipelineBuilds returns a page of builds of the pipeline matching the filter,
+	// items are filtered again on the client so a page can contain less items than its size.
+	ListPipelineBuilds(source, owner, name string, filter PipelineFilter) (*PagedBuildsResponse, error)
+	// GetPipelineBuild returns the build of the pipeline using its revision or ID
+	GetPipelineBuild(source, owner, name, revisionID string) (*contracts.Build, error)
+	// ListPipelineReleases returns a page of releases of the pipeline matching the filter, the branch is ignored.
+	ListPipelineReleases(source, owner, name string, filter PipelineFilter) (*PagedReleasesResponse, error)
+	// GetPipelineRelease returns the release of the pipeline using its ID
+	GetPipelineRelease(source, owner, name, releaseID string) (*contracts.Release, error)
+	// GetLogs returns the logs of the build or release using its ID, use the Request.From or Request.To fields
+	// for the source or target pipeline.
+	GetLogs(logType LogType, source, owner, name, id string) (*Log, error)
+	// StreamLogs decodes the logs of the build or release using its ID one step at a time and calls fn for each step
+	// in order, it stops at the first error returned by fn.
+	StreamLogs(logType LogType, source, owner, name, id string, fn func(step *contracts.BuildLogStep) error) error
+	// StreamRawLogs is like StreamLogs but calls fn with the JSON of each step as it is served, so no field is lost
+	// by decoding. The step is only valid until fn returns.
+	StreamRawLogs(logType LogType, source, owner, name, id string, fn func(step json.RawMessage) error) error
 	// UnArchivePipeline un-archives the pipeline
 	UnArchivePipeline(source, owner, repo string) error
 	// ArchivePipeline archives the pipeline
@@ -61,6 +90,7 @@ type client struct {
 	httpClient
 	bearerAuth
 	serverURL string
+	metrics   *Metrics
 }
 
 type authResponse struct {
@@ -84,28 +114,44 @@ func NewClient(serverURL, clientID, clientSecret string) Client {
 	}
 }
 
-// httpGet request for the given api endpoint with optional body
-func (c *client) httpGet(api string, body any) (*http.Response, error) {
-	return c.request("GET", _urlJoin(c.serverURL, api), body)
+// NewClientWithMetrics returns a new migration API Client for estafette-ci-api which records request latencies in metrics
+func NewClientWithMetrics(serverURL, clientID, clientSecret string, metrics *Metrics) Client {
+	c := NewClient(serverURL, clientID, clientSecret).(*client)
+	c.metrics = metrics
+	return c
 }
 
-// httpPost request for the given api endpoint with optional body
-func (c *client) httpPost(api string, body any) (*http.Response, error) {
-	return c.request("POST", _urlJoin(c.serverURL, api), body)
+// httpGet request for the given api endpoint with optional body, endpoint names the request in metrics
+func (c *client) httpGet(endpoint, api string, body any) (*http.Response, error) {
+	return c.request(endpoint, "GET", c.url(api), body)
 }
 
-// httpPut request for the given api endpoint with optional body
-func (c *client) httpPut(join string, t interface{}) (*http.Response, error) {
-	return c.request("PUT", _urlJoin(c.serverURL, join), t)
+// httpPost request for the given api endpoint with optional body, endpoint names the request in metrics
+func (c *client) httpPost(endpoint, api string, body any) (*http.Response, error) {
+	return c.request(endpoint, "POST", c.url(api), body)
 }
 
-// httpDelete request for the given api endpoint with optional body
-func (c *client) httpDelete(api string, body any) (*http.Response, error) {
-	return c.request("DELETE", _urlJoin(c.serverURL, api), body)
+// httpPut request for the given api endpoint with optional body, endpoint names the request in metrics
+func (c *client) httpPut(endpoint, api string, body any) (*http.Response, error) {
+	return c.request(endpoint, "PUT", c.url(api), body)
+}
+
+// httpDelete request for the given api endpoint with optional body, endpoint names the request in metrics
+func (c *client) httpDelete(endpoint, api string, body any) (*http.Response, error) {
+	return c.request(endpoint, "DELETE", c.url(api), body)
+}
+
+// url of the api endpoint on the server, api can contain a query built with _withQuery
+func (c *client) url(api string) string {
+	path, query, _ := strings.Cut(api, "?")
+	if query == "" {
+		return _urlJoin(c.serverURL, path)
+	}
+	return _urlJoin(c.serverURL, path) + "?" + query
 }
 
 // request handles request body encoding if provided and authentication if token has expired
-func (c *client) request(method, url string, body any) (*http.Response, error) {
+func (c *client) request(endpoint, method, url string, body any) (*http.Response, error) {
 	var httpReq *http.Request
 	var err error
 	if body != nil {
@@ -128,7 +174,7 @@ func (c *client) request(method, url string, body any) (*http.Response, error) {
 	httpReq.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token))
 	httpReq.Header.Add("Content-Type", "application/json")
 	var res *http.Response
-	res, err = c.Do(httpReq)
+	res, err = c.do(endpoint, httpReq)
 	if err != nil {
 		return res, fmt.Errorf("error while executing http request [%s]%s %v: %w", method, url, body, err)
 	}
@@ -144,7 +190,7 @@ func (c *client) authenticate() error {
 		return fmt.Errorf("error while creating authentication request: %w", err)
 	}
 	var res *http.Response
-	if res, err = c.Do(authReq); err != nil {
+	if res, err = c.do("authenticate", authReq); err != nil {
 		return fmt.Errorf("error while authenticatiing: %w", err)
 	}
 	var data []byte
@@ -160,11 +206,23 @@ func (c *client) authenticate() error {
 	return nil
 }
 
+// do executes the request and records its latency for the given endpoint
+func (c *client) do(endpoint string, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := c.Do(req)
+	statusCode := 0
+	if res != nil {
+		statusCode = res.StatusCode
+	}
+	c.metrics.observeRequest(endpoint, statusCode, time.Since(start))
+	return res, err
+}
+
 func (c *client) Queue(request Request) (*Task, error) {
 	if request.CallbackURL != nil && *request.CallbackURL == "" {
 		request.CallbackURL = nil
 	}
-	res, err := c.httpPost(migrationAPI, request)
+	res, err := c.httpPost("queue", migrationAPI, request)
 	if err != nil {
 		return nil, fmt.Errorf("queue api: error while executing request: %w", err)
 	}
@@ -181,7 +239,7 @@ func (c *client) Queue(request Request) (*Task, error) {
 }
 
 func (c *client) GetMigrationByID(taskID string) (*Task, error) {
-	res, err := c.httpGet(_urlJoin(migrationAPI, taskID), nil)
+	res, err := c.httpGet("getMigrationByID", _apiPath(migrationAPI, taskID), nil)
 	if err != nil {
 		return nil, fmt.Errorf("getMigrationByID api: error while executing request: %w", err)
 	}
@@ -198,7 +256,7 @@ func (c *client) GetMigrationByID(taskID string) (*Task, error) {
 }
 
 func (c *client) RollbackMigration(taskID string) (*Changes, error) {
-	res, err := c.httpDelete(_urlJoin(migrationAPI, taskID), nil)
+	res, err := c.httpDelete("rollbackMigration", _apiPath(migrationAPI, taskID), nil)
 	if err != nil {
 		return nil, fmt.Errorf("rollbackMigration api: error while executing request: %w", err)
 	}
@@ -214,8 +272,25 @@ func (c *client) RollbackMigration(taskID string) (*Changes, error) {
 	return changes, nil
 }
 
+func (c *client) RollbackMigrationChanges(taskID string, entity EntityType, toIDs []int64) (*Changes, error) {
+	res, err := c.httpDelete("rollbackMigrationChanges", _apiPath(migrationAPI, taskID, "changes", string(entity)), toIDs)
+	if err != nil {
+		return nil, fmt.Errorf("rollbackMigrationChanges api: error while executing request: %w", err)
+	}
+	var body []byte
+	body, err = _successful(res)
+	if err != nil {
+		return nil, fmt.Errorf("rollbackMigrationChanges api: %w", err)
+	}
+	changes := &Changes{}
+	if err = json.Unmarshal(body, changes); err != nil {
+		return nil, fmt.Errorf("rollbackMigrationChanges api: error while unmarshalling response: %w", err)
+	}
+	return changes, nil
+}
+
 func (c *client) GetMigrationByFromRepo(source, owner, name string) (*Task, error) {
-	res, err := c.httpGet(_urlJoin(migrationAPI, "from", source, owner, name), nil)
+	res, err := c.httpGet("getMigrationByFromRepo", _apiPath(migrationAPI, "from", source, owner, name), nil)
 	if err != nil {
 		return nil, fmt.Errorf("getMigrationByFromRepo api: error while executing request: %w", err)
 	}
@@ -232,7 +307,7 @@ func (c *client) GetMigrationByFromRepo(source, owner, name string) (*Task, erro
 }
 
 func (c *client) GetMigrations() ([]*Task, error) {
-	res, err := c.httpGet(migrationAPI, nil)
+	res, err := c.httpGet("getMigrations", migrationAPI, nil)
 	if err != nil {
 		return nil, fmt.Errorf("getMigrations api: error while executing request: %w", err)
 	}
@@ -248,48 +323,218 @@ func (c *client) GetMigrations() ([]*Task, error) {
 	return tasks, nil
 }
 
-func (c *client) GetPipelineBuildStatus(source, owner, name, branch, revisionID string) (string, error) {
-	url := _urlJoin(pipelinesAPI, source, owner, name, "builds")
-	if revisionID != "" {
-		url = _urlJoin(url, revisionID)
+func (c *client) GetMigrationHistory(taskID string) ([]HistoryEntry, error) {
+	res, err := c.httpGet("getMigrationHistory", _apiPath(migrationAPI, taskID, "history"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("getMigrationHistory api: error while executing request: %w", err)
 	}
-
-	res, err := c.httpGet(url, nil)
+	var body []byte
+	body, err = _successful(res)
 	if err != nil {
-		return "", fmt.Errorf("getPipelineStatus api: error while executing request: %w", err)
+		return nil, fmt.Errorf("getMigrationHistory api: %w", err)
+	}
+	history := make([]HistoryEntry, 0)
+	if err = json.Unmarshal(body, &history); err != nil {
+		return nil, fmt.Errorf("getMigrationHistory api: error while unmarshalling response: %w", err)
 	}
+	return history, nil
+}
 
-	body, err := _successful(res)
+func (c *client) GetMigrationChanges(taskID string, entity EntityType) ([]Change, error) {
+	res, err := c.httpGet("getMigrationChanges", _apiPath(migrationAPI, taskID, "changes", string(entity)), nil)
 	if err != nil {
-		return "", fmt.Errorf("getPipelineStatus api: %w", err)
+		return nil, fmt.Errorf("getMigrationChanges api: error while executing request: %w", err)
 	}
+	var body []byte
+	body, err = _successful(res)
+	if err != nil {
+		return nil, fmt.Errorf("getMigrationChanges api: %w", err)
+	}
+	changes := make([]Change, 0)
+	if err = json.Unmarshal(body, &changes); err != nil {
+		return nil, fmt.Errorf("getMigrationChanges api: error while unmarshalling response: %w", err)
+	}
+	return changes, nil
+}
 
+func (c *client) GetPipelineBuildStatus(source, owner, name, branch, revisionID string) (contracts.Status, error) {
 	if revisionID != "" {
-		var buildResponse *contracts.Build
-		if err := json.Unmarshal(body, &buildResponse); err != nil {
-			return "", fmt.Errorf("getPipelineStatus api: error while unmarshalling response: %w", err)
+		build, err := c.GetPipelineBuild(source, owner, name, revisionID)
+		if err != nil {
+			return contracts.StatusUnknown, fmt.Errorf("getPipelineStatus api: %w", err)
+		}
+		if build == nil {
+			return contracts.StatusUnknown, nil
 		}
-		return string(buildResponse.BuildStatus), nil
+		return build.BuildStatus, nil
 	}
 
-	var pagedBuildResponse PagedBuildsResponse
-	if err := json.Unmarshal(body, &pagedBuildResponse); err != nil {
-		return "", fmt.Errorf("getPipelineStatus api: error while unmarshalling response: %w", err)
+	var latest *contracts.Build
+	filter := PipelineFilter{Branch: branch}
+	for filter.Page = 1; ; filter.Page++ {
+		builds, err := c.ListPipelineBuilds(source, owner, name, filter)
+		if err != nil {
+			return contracts.StatusUnknown, fmt.Errorf("getPipelineStatus api: %w", err)
+		}
+		for _, build := range builds.Items {
+			if latest == nil || _buildTime(build).After(_buildTime(latest)) {
+				latest = build
+			}
+		}
+		if filter.Page >= builds.Pagination.TotalPages {
+			break
+		}
+	}
+	if latest == nil {
+		return contracts.StatusUnknown, nil
 	}
+	return latest.BuildStatus, nil
+}
 
-	// Sort builds by creation time in descending order
-	sort.Slice(pagedBuildResponse.Items, func(i, j int) bool {
-		return pagedBuildResponse.Items[i].StartedAt.After(*pagedBuildResponse.Items[j].StartedAt)
-	})
+func (c *client) ListPipelineBuilds(source, owner, name string, filter PipelineFilter) (*PagedBuildsResponse, error) {
+	query := filter.query()
+	res, err := c.httpGet("listPipelineBuilds", _withQuery(_apiPath(pipelinesAPI, source, owner, name, "builds"), query), nil)
+	if err != nil {
+		return nil, fmt.Errorf("listPipelineBuilds api: error while executing request for page %s: %w", query.Get("page[number]"), err)
+	}
+	var body []byte
+	body, err = _successful(res)
+	if err != nil {
+		return nil, fmt.Errorf("listPipelineBuilds api: page %s: %w", query.Get("page[number]"), err)
+	}
+	builds := &PagedBuildsResponse{}
+	if err = json.Unmarshal(body, builds); err != nil {
+		return nil, fmt.Errorf("listPipelineBuilds api: error while unmarshalling response of page %s: %w", query.Get("page[number]"), err)
+	}
+	items := make([]*contracts.Build, 0, len(builds.Items))
+	for _, build := range builds.Items {
+		if filter.matchesBuild(build) {
+			items = append(items, build)
+		}
+	}
+	builds.Items = items
+	return builds, nil
+}
 
-	// Get the latest build for the branch
-	for _, build := range pagedBuildResponse.Items {
-		if build.RepoBranch == branch {
-			return string(build.BuildStatus), nil
+func (c *client) GetPipelineBuild(source, owner, name, revisionID string) (*contracts.Build, error) {
+	res, err := c.httpGet("getPipelineBuild", _apiPath(pipelinesAPI, source, owner, name, "builds", revisionID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("getPipelineBuild api: error while executing request: %w", err)
+	}
+	var body []byte
+	body, err = _successful(res)
+	if err != nil {
+		return nil, fmt.Errorf("getPipelineBuild api: %w", err)
+	}
+	var build *contracts.Build
+	if err = json.Unmarshal(body, &build); err != nil {
+		return nil, fmt.Errorf("getPipelineBuild api: error while unmarshalling response: %w", err)
+	}
+	return build, nil
+}
+
+func (c *client) ListPipelineReleases(source, owner, name string, filter PipelineFilter) (*PagedReleasesResponse, error) {
+	filter.Branch = ""
+	query := filter.query()
+	res, err := c.httpGet("listPipelineReleases", _withQuery(_apiPath(pipelinesAPI, source, owner, name, "releases"), query), nil)
+	if err != nil {
+		return nil, fmt.Errorf("listPipelineReleases api: error while executing request for page %s: %w", query.Get("page[number]"), err)
+	}
+	var body []byte
+	body, err = _successful(res)
+	if err != nil {
+		return nil, fmt.Errorf("listPipelineReleases api: page %s: %w", query.Get("page[number]"), err)
+	}
+	releases := &PagedReleasesResponse{}
+	if err = json.Unmarshal(body, releases); err != nil {
+		return nil, fmt.Errorf("listPipelineReleases api: error while unmarshalling response of page %s: %w", query.Get("page[number]"), err)
+	}
+	items := make([]*contracts.Release, 0, len(releases.Items))
+	for _, release := range releases.Items {
+		if filter.matchesRelease(release) {
+			items = append(items, release)
 		}
 	}
+	releases.Items = items
+	return releases, nil
+}
 
-	return "", nil
+func (c *client) GetPipelineRelease(source, owner, name, releaseID string) (*contracts.Release, error) {
+	res, err := c.httpGet("getPipelineRelease", _apiPath(pipelinesAPI, source, owner, name, "releases", releaseID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("getPipelineRelease api: error while executing request: %w", err)
+	}
+	var body []byte
+	body, err = _successful(res)
+	if err != nil {
+		return nil, fmt.Errorf("getPipelineRelease api: %w", err)
+	}
+	var release *contracts.Release
+	if err = json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("getPipelineRelease api: error while unmarshalling response: %w", err)
+	}
+	return release, nil
+}
+
+func (c *client) GetLogs(logType LogType, source, owner, name, id string) (*Log, error) {
+	if !logType.IsValid() {
+		return nil, fmt.Errorf("getLogs api: %w: %q", ErrUnknownLogType, logType)
+	}
+	res, err := c.httpGet("getLogs", _apiPath(pipelinesAPI, source, owner, name, string(logType), id, "logs"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("getLogs api: error while executing request: %w", err)
+	}
+	var body []byte
+	body, err = _successful(res)
+	if err != nil {
+		return nil, fmt.Errorf("getLogs api: %w", err)
+	}
+	var response struct {
+		ID         string                    `json:"id"`
+		BuildID    string                    `json:"buildID"`
+		ReleaseID  string                    `json:"releaseID"`
+		Steps      []*contracts.BuildLogStep `json:"steps"`
+		InsertedAt time.Time                 `json:"insertedAt"`
+	}
+	if err = json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("getLogs api: error while unmarshalling response: %w", err)
+	}
+	logs := &Log{ID: response.ID, Type: logType, ParentID: response.BuildID, Steps: response.Steps, InsertedAt: response.InsertedAt}
+	if logType == ReleaseLog {
+		logs.ParentID = response.ReleaseID
+	}
+	return logs, nil
+}
+
+func (c *client) StreamLogs(logType LogType, source, owner, name, id string, fn func(step *contracts.BuildLogStep) error) error {
+	return c.streamLogs("streamLogs", logType, source, owner, name, id, func(decoder *json.Decoder) error {
+		return _decodeSteps(decoder, fn)
+	})
+}
+
+func (c *client) StreamRawLogs(logType LogType, source, owner, name, id string, fn func(step json.RawMessage) error) error {
+	return c.streamLogs("streamRawLogs", logType, source, owner, name, id, func(decoder *json.Decoder) error {
+		return _decodeRawSteps(decoder, fn)
+	})
+}
+
+func (c *client) streamLogs(endpoint string, logType LogType, source, owner, name, id string, decode func(decoder *json.Decoder) error) error {
+	if !logType.IsValid() {
+		return fmt.Errorf("%s api: %w: %q", endpoint, ErrUnknownLogType, logType)
+	}
+	res, err := c.httpGet(endpoint, _apiPath(pipelinesAPI, source, owner, name, string(logType), id, "logs"), nil)
+	if err != nil {
+		return fmt.Errorf("%s api: error while executing request: %w", endpoint, err)
+	}
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		_, err = _successful(res)
+		return fmt.Errorf("%s api: %w", endpoint, err)
+	}
+	defer _close(res.Body)
+	if err = decode(json.NewDecoder(res.Body)); err != nil {
+		return fmt.Errorf("%s api: %w", endpoint, err)
+	}
+	return nil
 }
 
 func (c *client) UnArchivePipeline(source, owner, repo string) error {
@@ -301,13 +546,11 @@ func (c *client) ArchivePipeline(source, owner, repo string) error {
 }
 
 func (c *client) doArchivalPipeline(source, owner, repo string, archived bool) error {
-	url := fmt.Sprintf("/from/%s/%s/%s", source, owner, repo)
+	action := "unarchive"
 	if archived {
-		url = fmt.Sprintf("%s/archive", url)
-	} else {
-		url = fmt.Sprintf("%s/unarchive", url)
+		action = "archive"
 	}
-	res, err := c.httpPut(_urlJoin(migrationAPI, url), nil)
+	res, err := c.httpPut("pipelineArchival", _apiPath(migrationAPI, "from", source, owner, repo, action), nil)
 	if err != nil {
 		return fmt.Errorf("pipelineArchival api: error while executing request: %w", err)
 	}
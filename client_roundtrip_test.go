@@ -0,0 +1,238 @@
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	contracts "github.com/estafette/estafette-ci-contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// _roundTripServer responds with body to every api request and records the unescaped path segments of the last request.
+func _roundTripServer(t *testing.T, body string) (*httptest.Server, *[]string, *string) {
+	t.Helper()
+	segments := make([]string, 0)
+	method := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/auth/client/login" {
+			_, _ = w.Write([]byte(`{"token":"test-token"}`))
+			return
+		}
+		method = r.Method
+		segments = segments[:0]
+		for _, segment := range strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/") {
+			unescaped, err := url.PathUnescape(segment)
+			if err != nil {
+				t.Errorf("invalid path segment %q: %v", segment, err)
+			}
+			segments = append(segments, unescaped)
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &segments, &method
+}
+
+func TestClient_RoundTrip_PathSegments(t *testing.T) {
+	repositories := []struct {
+		name   string
+		source string
+		owner  string
+		repo   string
+	}{
+		{name: "github", source: "github.com", owner: "estafette", repo: "migration"},
+		{name: "gitlab subgroup", source: "gitlab.com", owner: "group/subgroup/team", repo: "migration"},
+		{name: "azure project", source: "dev.azure.com", owner: "organization/My Project", repo: "migration"},
+		{name: "spaces and percent", source: "github.com", owner: "owner 1", repo: "repo%20name 100%"},
+		{name: "reserved characters", source: "github.com", owner: "owner?x=1", repo: "repo#fragment;v=1"},
+		{name: "dot segments", source: "github.com", owner: ".", repo: ".."},
+		{name: "dots", source: "github.com", owner: "...", repo: "../repo"},
+	}
+	for _, repository := range repositories {
+		tests := []struct {
+			name     string
+			body     string
+			method   string
+			segments []string
+			do       func(c Client) error
+		}{
+			{
+				name:     "GetMigrationByID",
+				body:     `{"id":"` + repository.owner + `"}`,
+				method:   "GET",
+				segments: []string{"api", "migrations", repository.owner},
+				do: func(c Client) error {
+					_, err := c.GetMigrationByID(repository.owner)
+					return err
+				},
+			},
+			{
+				name:     "RollbackMigration",
+				body:     `{}`,
+				method:   "DELETE",
+				segments: []string{"api", "migrations", repository.owner},
+				do: func(c Client) error {
+					_, err := c.RollbackMigration(repository.owner)
+					return err
+				},
+			},
+			{
+				name:     "RollbackMigrationChanges",
+				body:     `{"builds":1}`,
+				method:   "DELETE",
+				segments: []string{"api", "migrations", repository.owner, "changes", "build"},
+				do: func(c Client) error {
+					changes, err := c.RollbackMigrationChanges(repository.owner, EntityBuild, []int64{11})
+					if err == nil && changes.Builds != 1 {
+						return fmt.Errorf("unexpected changes %v", changes)
+					}
+					return err
+				},
+			},
+			{
+				name:     "GetMigrationHistory",
+				body:     `[]`,
+				method:   "GET",
+				segments: []string{"api", "migrations", repository.owner, "history"},
+				do: func(c Client) error {
+					_, err := c.GetMigrationHistory(repository.owner)
+					return err
+				},
+			},
+			{
+				name:     "GetMigrationChanges",
+				body:     `[{"FromID":1,"ToID":2}]`,
+				method:   "GET",
+				segments: []string{"api", "migrations", repository.owner, "changes", "build"},
+				do: func(c Client) error {
+					changes, err := c.GetMigrationChanges(repository.owner, EntityBuild)
+					if err == nil && (len(changes) != 1 || changes[0] != Change{FromID: 1, ToID: 2}) {
+						return fmt.Errorf("unexpected changes %v", changes)
+					}
+					return err
+				},
+			},
+			{
+				name:     "GetMigrationByFromRepo",
+				body:     `{}`,
+				method:   "GET",
+				segments: []string{"api", "migrations", "from", repository.source, repository.owner, repository.repo},
+				do: func(c Client) error {
+					_, err := c.GetMigrationByFromRepo(repository.source, repository.owner, repository.repo)
+					return err
+				},
+			},
+			{
+				name:     "GetPipelineBuildStatus",
+				body:     `{"buildStatus":"succeeded"}`,
+				method:   "GET",
+				segments: []string{"api", "pipelines", repository.source, repository.owner, repository.repo, "builds", "revision/1"},
+				do: func(c Client) error {
+					_, err := c.GetPipelineBuildStatus(repository.source, repository.owner, repository.repo, "main", "revision/1")
+					return err
+				},
+			},
+			{
+				name:     "GetPipelineBuild",
+				body:     `{"id":"1"}`,
+				method:   "GET",
+				segments: []string{"api", "pipelines", repository.source, repository.owner, repository.repo, "builds", "revision/1"},
+				do: func(c Client) error {
+					_, err := c.GetPipelineBuild(repository.source, repository.owner, repository.repo, "revision/1")
+					return err
+				},
+			},
+			{
+				name:     "ListPipelineBuilds",
+				body:     `{"items":[]}`,
+				method:   "GET",
+				segments: []string{"api", "pipelines", repository.source, repository.owner, repository.repo, "builds"},
+				do: func(c Client) error {
+					_, err := c.ListPipelineBuilds(repository.source, repository.owner, repository.repo, PipelineFilter{Branch: "feature/x"})
+					return err
+				},
+			},
+			{
+				name:     "GetPipelineRelease",
+				body:     `{"id":"1"}`,
+				method:   "GET",
+				segments: []string{"api", "pipelines", repository.source, repository.owner, repository.repo, "releases", "1"},
+				do: func(c Client) error {
+					_, err := c.GetPipelineRelease(repository.source, repository.owner, repository.repo, "1")
+					return err
+				},
+			},
+			{
+				name:     "ListPipelineReleases",
+				body:     `{"items":[]}`,
+				method:   "GET",
+				segments: []string{"api", "pipelines", repository.source, repository.owner, repository.repo, "releases"},
+				do: func(c Client) error {
+					_, err := c.ListPipelineReleases(repository.source, repository.owner, repository.repo, PipelineFilter{})
+					return err
+				},
+			},
+			{
+				name:     "GetLogs",
+				body:     `{"id":"1","steps":[]}`,
+				method:   "GET",
+				segments: []string{"api", "pipelines", repository.source, repository.owner, repository.repo, "builds", "1", "logs"},
+				do: func(c Client) error {
+					_, err := c.GetLogs(BuildLog, repository.source, repository.owner, repository.repo, "1")
+					return err
+				},
+			},
+			{
+				name:     "StreamLogs",
+				body:     `{"id":"1","steps":[]}`,
+				method:   "GET",
+				segments: []string{"api", "pipelines", repository.source, repository.owner, repository.repo, "releases", "1", "logs"},
+				do: func(c Client) error {
+					return c.StreamLogs(ReleaseLog, repository.source, repository.owner, repository.repo, "1", func(*contracts.BuildLogStep) error { return nil })
+				},
+			},
+			{
+				name:     "StreamRawLogs",
+				body:     `{"id":"1","steps":[]}`,
+				method:   "GET",
+				segments: []string{"api", "pipelines", repository.source, repository.owner, repository.repo, "builds", "1", "logs"},
+				do: func(c Client) error {
+					return c.StreamRawLogs(BuildLog, repository.source, repository.owner, repository.repo, "1", func(json.RawMessage) error { return nil })
+				},
+			},
+			{
+				name:     "ArchivePipeline",
+				body:     `{}`,
+				method:   "PUT",
+				segments: []string{"api", "migrations", "from", repository.source, repository.owner, repository.repo, "archive"},
+				do: func(c Client) error {
+					return c.ArchivePipeline(repository.source, repository.owner, repository.repo)
+				},
+			},
+			{
+				name:     "UnArchivePipeline",
+				body:     `{}`,
+				method:   "PUT",
+				segments: []string{"api", "migrations", "from", repository.source, repository.owner, repository.repo, "unarchive"},
+				do: func(c Client) error {
+					return c.UnArchivePipeline(repository.source, repository.owner, repository.repo)
+				},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(repository.name+"/"+tt.name, func(t *testing.T) {
+				shouldBe := assert.New(t)
+				server, segments, method := _roundTripServer(t, tt.body)
+				c := NewClient(server.URL, "test-clientID", "test-clientSecret")
+				shouldBe.Nil(tt.do(c))
+				shouldBe.Equal(tt.method, *method)
+				shouldBe.Equal(tt.segments, *segments)
+			})
+		}
+	}
+}
@@ -1,12 +1,19 @@
 package migration
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	contracts "github.com/estafette/estafette-ci-contracts"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -176,6 +183,30 @@ func TestClient_GetMigrationByFromRepo_Success(t *testing.T) {
 	}
 }
 
+func TestClient_GetMigrationByFromRepo_NestedOwner(t *testing.T) {
+	mockedClient := &mockClient{}
+	c := &client{
+		httpClient: mockedClient,
+		bearerAuth: bearerAuth{
+			clientID:     "test-clientID",
+			clientSecret: "test-clientSecret",
+		},
+		serverURL: "http://localhost:80",
+	}
+	mockAuth(mockedClient).Once()
+	mockedClient.
+		On("Do", mock.Anything).
+		Return(&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"id":"test-123"}`))}, nil).
+		Once()
+	shouldBe := assert.New(t)
+	_, err := c.GetMigrationByFromRepo("gitlab.com", "group/subgroup", "my repo")
+	shouldBe.Nil(err)
+	if mockedClient.AssertExpectations(t) {
+		migrationReq := mockedClient.Calls[1].Arguments[0].(*http.Request)
+		shouldBe.Equal("http://localhost:80/api/migrations/from/gitlab.com/group%2Fsubgroup/my%20repo", migrationReq.URL.String())
+	}
+}
+
 func TestClient_GetMigrationByFromRepo_Failure(t *testing.T) {
 	mockedClient := &mockClient{}
 	c := &client{
@@ -251,6 +282,35 @@ func TestClient_Rollback_Failure(t *testing.T) {
 	shouldBe.Equal(fmt.Errorf("rollbackMigration api: %w", fmt.Errorf(`responded with status: 404 Not Found, body: {"code":404,"message":"migration task not found"}`)), err)
 }
 
+func TestClient_RollbackMigrationChanges(t *testing.T) {
+	mockedClient := &mockClient{}
+	c := &client{
+		httpClient: mockedClient,
+		bearerAuth: bearerAuth{
+			clientID:     "test-clientID",
+			clientSecret: "test-clientSecret",
+		},
+		serverURL: "http://localhost:80",
+	}
+	mockAuth(mockedClient).Once()
+	mockedClient.
+		On("Do", mock.Anything).
+		Return(&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"builds": 2,"buildLogs": 2}`))}, nil).
+		Once()
+	shouldBe := assert.New(t)
+	changes, err := c.RollbackMigrationChanges("test-123", EntityBuild, []int64{11, 12})
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(&Changes{Builds: 2, BuildLogs: 2}, changes)
+	}
+	if mockedClient.AssertExpectations(t) {
+		migrationReq := mockedClient.Calls[1].Arguments[0].(*http.Request)
+		shouldBe.Equal("DELETE", migrationReq.Method)
+		shouldBe.Equal("http://localhost:80/api/migrations/test-123/changes/build", migrationReq.URL.String())
+		body, _ := io.ReadAll(migrationReq.Body)
+		shouldBe.Equal("[11,12]", string(body))
+	}
+}
+
 func TestClient_archival(t *testing.T) {
 	mockedClient := &mockClient{}
 	c := &client{
@@ -365,6 +425,42 @@ func TestClient_GetMigrations_Success(t *testing.T) {
 	}
 }
 
+func TestClient_GetMigrationHistory_Success(t *testing.T) {
+	mockedClient := &mockClient{}
+	c := &client{
+		httpClient: mockedClient,
+		bearerAuth: bearerAuth{
+			clientID:     "test-clientID",
+			clientSecret: "test-clientSecret",
+		},
+		serverURL: "http://localhost:80",
+	}
+	mockAuth(mockedClient).Once()
+	mockedClient.
+		On("Do", mock.Anything).
+		Return(&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`[
+			{"stage":"releases","step":"releases_done","status":"in_progress","at":"2020-01-01T00:00:00Z","duration":10,"workerID":"worker-1"},
+			{"stage":"builds","step":"builds_failed","status":"failed","at":"2020-01-01T00:01:00Z","duration":20,"error":"test error","workerID":"worker-1"}
+]`))}, nil).
+		Once()
+	shouldBe := assert.New(t)
+	history, err := c.GetMigrationHistory("test-123")
+	errorDetails := "test error"
+	if shouldBe.Nil(err) {
+		shouldBe.Equal([]HistoryEntry{
+			{Stage: ReleasesStage, Step: StepReleasesDone, Status: StatusInProgress, At: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Duration: 10, WorkerID: "worker-1"},
+			{Stage: BuildsStage, Step: StepBuildsFailed, Status: StatusFailed, At: time.Date(2020, 1, 1, 0, 1, 0, 0, time.UTC), Duration: 20, Error: &errorDetails, WorkerID: "worker-1"},
+		}, history)
+	}
+	if mockedClient.AssertExpectations(t) {
+		migrationReq := mockedClient.Calls[1].Arguments[0].(*http.Request)
+		shouldBe.NotNil(migrationReq)
+		shouldBe.Equal("GET", migrationReq.Method)
+		shouldBe.Equal("http://localhost:80/api/migrations/test-123/history", migrationReq.URL.String())
+		shouldBe.Nil(migrationReq.Body)
+	}
+}
+
 func TestClient_GetPipelineBuildStatus_Success(t *testing.T) {
 	mockedClient := &mockClient{}
 	c := &client{
@@ -383,17 +479,166 @@ func TestClient_GetPipelineBuildStatus_Success(t *testing.T) {
 	shouldBe := assert.New(t)
 	status, err := c.GetPipelineBuildStatus("github.com", "xivart", "scm-migrator-test1", "scm-migrator", "")
 	if shouldBe.Nil(err) {
-		shouldBe.Equal("succeeded", status)
+		shouldBe.Equal(contracts.StatusSucceeded, status)
 	}
 	if mockedClient.AssertExpectations(t) {
 		pipelineReq := mockedClient.Calls[1].Arguments[0].(*http.Request)
 		shouldBe.NotNil(pipelineReq)
 		shouldBe.Equal("GET", pipelineReq.Method)
-		shouldBe.Equal("http://localhost:80/api/pipelines/github.com/xivart/scm-migrator-test1/builds", pipelineReq.URL.String())
+		shouldBe.Equal("http://localhost:80/api/pipelines/github.com/xivart/scm-migrator-test1/builds?filter%5Bbranch%5D=scm-migrator&page%5Bnumber%5D=1&page%5Bsize%5D=100", pipelineReq.URL.String())
 		shouldBe.Nil(pipelineReq.Body)
 	}
 }
 
+func TestClient_GetPipelineBuildStatus_Pages(t *testing.T) {
+	pages := []string{
+		`{"items":[{"repoBranch":"main","buildStatus":"failed","startedAt":"2023-01-01T10:00:00Z"},{"repoBranch":"feature","buildStatus":"running","startedAt":"2023-01-03T10:00:00Z"}],"pagination":{"page":1,"size":2,"totalPages":3,"totalItems":5}}`,
+		`{"items":[{"repoBranch":"main","buildStatus":"succeeded","startedAt":"2023-01-02T10:00:00Z"},{"repoBranch":"main","buildStatus":"pending","insertedAt":"2023-01-01T12:00:00Z"}],"pagination":{"page":2,"size":2,"totalPages":3,"totalItems":5}}`,
+		`{"items":[{"repoBranch":"release","buildStatus":"succeeded","startedAt":"2023-01-04T10:00:00Z"}],"pagination":{"page":3,"size":2,"totalPages":3,"totalItems":5}}`,
+	}
+	requested := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/auth/client/login" {
+			_, _ = w.Write([]byte(`{"token":"test-token"}`))
+			return
+		}
+		requested = append(requested, r.URL.Query().Get("filter[branch]")+"/"+r.URL.Query().Get("page[number]"))
+		page, err := strconv.Atoi(r.URL.Query().Get("page[number]"))
+		assert.Nil(t, err)
+		_, _ = w.Write([]byte(pages[page-1]))
+	}))
+	defer server.Close()
+	shouldBe := assert.New(t)
+	c := NewClient(server.URL, "test-clientID", "test-clientSecret")
+	status, err := c.GetPipelineBuildStatus("github.com", "estafette", "migration", "main", "")
+	shouldBe.Nil(err)
+	shouldBe.Equal(contracts.StatusSucceeded, status)
+	shouldBe.Equal([]string{"main/1", "main/2", "main/3"}, requested)
+
+	status, err = c.GetPipelineBuildStatus("github.com", "estafette", "migration", "unknown", "")
+	shouldBe.Nil(err)
+	shouldBe.Equal(contracts.StatusUnknown, status)
+	shouldBe.Equal([]string{"main/1", "main/2", "main/3", "unknown/1", "unknown/2", "unknown/3"}, requested)
+}
+
+func TestClient_ListPipelineReleases(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/auth/client/login" {
+			_, _ = w.Write([]byte(`{"token":"test-token"}`))
+			return
+		}
+		query = r.URL.Query()
+		_, _ = w.Write([]byte(`{"items":[{"id":"1","releaseStatus":"succeeded","startedAt":"2023-01-02T10:00:00Z"},{"id":"2","releaseStatus":"failed","startedAt":"2023-01-02T11:00:00Z"},{"id":"3","releaseStatus":"succeeded","startedAt":"2022-12-31T10:00:00Z"}],"pagination":{"page":2,"size":3,"totalPages":4,"totalItems":12}}`))
+	}))
+	defer server.Close()
+	shouldBe := assert.New(t)
+	c := NewClient(server.URL, "test-clientID", "test-clientSecret")
+	releases, err := c.ListPipelineReleases("github.com", "estafette", "migration", PipelineFilter{
+		Branch:   "main",
+		Statuses: []contracts.Status{contracts.StatusSucceeded},
+		Since:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Page:     2,
+		Size:     3,
+	})
+	if shouldBe.Nil(err) {
+		shouldBe.Len(releases.Items, 1)
+		shouldBe.Equal("1", releases.Items[0].ID)
+		shouldBe.Equal(contracts.Pagination{Page: 2, Size: 3, TotalPages: 4, TotalItems: 12}, releases.Pagination)
+	}
+	shouldBe.Equal(url.Values{
+		"filter[status]": {"succeeded"},
+		"filter[since]":  {"2023-01-01T00:00:00Z"},
+		"page[number]":   {"2"},
+		"page[size]":     {"3"},
+	}, query)
+}
+
+func TestClient_GetPipelineBuild_Failure(t *testing.T) {
+	mockedClient := &mockClient{}
+	c := &client{
+		httpClient: mockedClient,
+		bearerAuth: bearerAuth{
+			clientID:     "test-clientID",
+			clientSecret: "test-clientSecret",
+		},
+		serverURL: "http://localhost:80",
+	}
+	mockAuth(mockedClient).Once()
+	mockedClient.
+		On("Do", mock.Anything).
+		Return(&http.Response{Status: "404 Not Found", StatusCode: 404, Body: io.NopCloser(strings.NewReader(`{"code":404,"message":"build not found"}`))}, nil).
+		Once()
+	shouldBe := assert.New(t)
+	build, err := c.GetPipelineBuild("github.com", "estafette", "migration", "abc")
+	shouldBe.Nil(build)
+	shouldBe.Equal(fmt.Errorf("getPipelineBuild api: %w", fmt.Errorf(`responded with status: 404 Not Found, body: {"code":404,"message":"build not found"}`)), err)
+}
+
+func TestClient_Logs(t *testing.T) {
+	const releaseLog = `{"id":"10","repoSource":"github.com","releaseID":"5","steps":[{"step":"prepare","logLines":[{"line":1,"text":"pulling"}],"status":"SUCCEEDED"},{"step":"deploy","logLines":[{"line":1,"text":"deploying"},{"line":2,"text":"done"}],"status":"SUCCEEDED"}],"insertedAt":"2023-01-01T10:00:00Z"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth/client/login":
+			_, _ = w.Write([]byte(`{"token":"test-token"}`))
+		case "/api/pipelines/github.com/estafette/migration/releases/5/logs":
+			_, _ = w.Write([]byte(releaseLog))
+		default:
+			http.Error(w, `{"code":404}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, "test-clientID", "test-clientSecret")
+	t.Run("GetLogs", func(t *testing.T) {
+		shouldBe := assert.New(t)
+		logs, err := c.GetLogs(ReleaseLog, "github.com", "estafette", "migration", "5")
+		if shouldBe.Nil(err) {
+			shouldBe.Equal("10", logs.ID)
+			shouldBe.Equal("5", logs.ParentID)
+			shouldBe.Equal(ReleaseLog, logs.Type)
+			shouldBe.Len(logs.Steps, 2)
+			shouldBe.Equal(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC), logs.InsertedAt)
+		}
+		_, err = c.GetLogs(BuildLog, "github.com", "estafette", "migration", "5")
+		shouldBe.NotNil(err)
+		_, err = c.GetLogs("deployments", "github.com", "estafette", "migration", "5")
+		shouldBe.True(errors.Is(err, ErrUnknownLogType))
+	})
+	t.Run("StreamLogs", func(t *testing.T) {
+		shouldBe := assert.New(t)
+		steps := make([]string, 0)
+		err := c.StreamLogs(ReleaseLog, "github.com", "estafette", "migration", "5", func(step *contracts.BuildLogStep) error {
+			steps = append(steps, fmt.Sprintf("%s:%d", step.Step, len(step.LogLines)))
+			return nil
+		})
+		shouldBe.Nil(err)
+		shouldBe.Equal([]string{"prepare:1", "deploy:2"}, steps)
+
+		stop := fmt.Errorf("stop")
+		err = c.StreamLogs(ReleaseLog, "github.com", "estafette", "migration", "5", func(step *contracts.BuildLogStep) error {
+			return stop
+		})
+		shouldBe.True(errors.Is(err, stop))
+		err = c.StreamLogs(BuildLog, "github.com", "estafette", "migration", "5", func(step *contracts.BuildLogStep) error {
+			return nil
+		})
+		shouldBe.NotNil(err)
+	})
+	t.Run("StreamRawLogs", func(t *testing.T) {
+		shouldBe := assert.New(t)
+		steps := make([]string, 0)
+		err := c.StreamRawLogs(ReleaseLog, "github.com", "estafette", "migration", "5", func(step json.RawMessage) error {
+			steps = append(steps, string(step))
+			return nil
+		})
+		shouldBe.Nil(err)
+		shouldBe.Equal([]string{
+			`{"step":"prepare","logLines":[{"line":1,"text":"pulling"}],"status":"SUCCEEDED"}`,
+			`{"step":"deploy","logLines":[{"line":1,"text":"deploying"},{"line":2,"text":"done"}],"status":"SUCCEEDED"}`,
+		}, steps)
+	})
+}
+
 func mockAuth(mockedClient *mockClient) *mock.Call {
 	return mockedClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
 		return req.URL.String() == "http://localhost:80/api/auth/client/login"
@@ -0,0 +1,64 @@
+package migration
+
+import "context"
+
+type contextKey int
+
+const (
+	metricsKey contextKey = iota
+	progressKey
+	workerIDKey
+	ledgerKey
+)
+
+// ContextWithMetrics returns a copy of ctx carrying the given Metrics, used by Stages and executors to record metrics.
+func ContextWithMetrics(ctx context.Context, metrics *Metrics) context.Context {
+	return context.WithValue(ctx, metricsKey, metrics)
+}
+
+// MetricsFrom returns the Metrics carried by ctx or nil if there are none, a nil *Metrics records nothing.
+func MetricsFrom(ctx context.Context) *Metrics {
+	if metrics, ok := ctx.Value(metricsKey).(*Metrics); ok {
+		return metrics
+	}
+	return nil
+}
+
+func contextWithProgress(ctx context.Context, reporter *ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey, reporter)
+}
+
+// ProgressFrom returns the ProgressReporter of the stage being executed or nil if there is none,
+// a nil *ProgressReporter ignores reports.
+func ProgressFrom(ctx context.Context) *ProgressReporter {
+	if reporter, ok := ctx.Value(progressKey).(*ProgressReporter); ok {
+		return reporter
+	}
+	return nil
+}
+
+// ContextWithWorkerID returns a copy of ctx carrying the ID of the worker executing the task, it is recorded in the task history.
+func ContextWithWorkerID(ctx context.Context, workerID string) context.Context {
+	return context.WithValue(ctx, workerIDKey, workerID)
+}
+
+// WorkerIDFrom returns the worker ID carried by ctx or an empty string if there is none.
+func WorkerIDFrom(ctx context.Context) string {
+	if workerID, ok := ctx.Value(workerIDKey).(string); ok {
+		return workerID
+	}
+	return ""
+}
+
+// ContextWithLedger returns a copy of ctx carrying the Ledger in which executors record changes, see RecordChanges.
+func ContextWithLedger(ctx context.Context, ledger Ledger) context.Context {
+	return context.WithValue(ctx, ledgerKey, ledger)
+}
+
+// LedgerFrom returns the Ledger carried by ctx or nil if there is none.
+func LedgerFrom(ctx context.Context) Ledger {
+	if ledger, ok := ctx.Value(ledgerKey).(Ledger); ok {
+		return ledger
+	}
+	return nil
+}
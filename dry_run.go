@@ -0,0 +1,58 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Plan of a dry-run, executors executed with Request.DryRun set add the changes they would make to it instead of writing them.
+type Plan struct {
+	// Changes projected to be migrated.
+	Changes Changes `json:"changes"`
+	// Existing rows of the target pipeline which conflict with the projected changes.
+	Existing Changes `json:"existing"`
+}
+
+// HasConflicts returns true if rows of the target pipeline already exist.
+func (p *Plan) HasConflicts() bool {
+	return p.Existing != Changes{}
+}
+
+// dryRun executes the stage without moving the task to another Step, executors add projected changes to Task.Plan.
+// The task is in progress while stages are executed and fails if a stage fails, CompletedExecutor completes it.
+// A stage interrupted because ctx is done leaves the task in progress, so it can be queued again.
+func (s *stage) dryRun(ctx context.Context, task *Task) bool {
+	if task.Plan == nil {
+		task.Plan = &Plan{}
+	}
+	start := time.Now()
+	if err := task.Transition(StatusInProgress, task.LastStep); err != nil {
+		s.dryRunFailed(task, err)
+		return false
+	}
+	stop := ProgressFrom(ctx).heartbeat(ctx)
+	err := s.execute(ctx, task)
+	stop()
+	if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		log.Warn().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Str("stage", string(s.Name())).Msg("stage dry-run interrupted")
+		return false
+	}
+	if err != nil {
+		if transitionErr := task.Transition(StatusFailed, task.LastStep); transitionErr != nil {
+			err = errors.Join(err, transitionErr)
+		}
+		s.dryRunFailed(task, err)
+		return false
+	}
+	log.Debug().Str("module", "github.com/estafette/migration").Str("taskID", task.ID).Str("stage", string(s.Name())).Dur("took", time.Since(start)).Msg("stage dry-run done")
+	return true
+}
+
+func (s *stage) dryRunFailed(task *Task, err error) {
+	errorDetails := err.Error()
+	task.ErrorDetails = &errorDetails
+	log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Str("stage", string(s.Name())).Msg("stage dry-run failed")
+}
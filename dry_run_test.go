@@ -0,0 +1,138 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStages_DryRun(t *testing.T) {
+	shouldBe := assert.New(t)
+	mockedUpdater := &mockUpdater{}
+	mockedUpdater.On("update", mock.Anything, mock.Anything).Return(nil)
+	task := _WaitingTask()
+	task.DryRun = true
+	planner := func(changes, existing Changes) Executor {
+		return func(_ context.Context, task *Task) error {
+			shouldBe.True(task.DryRun)
+			task.Plan.Changes.Add(changes)
+			task.Plan.Existing.Add(existing)
+			return nil
+		}
+	}
+	ss := NewStages(mockedUpdater.update, task).
+		Set(ReleasesStage, planner(Changes{Releases: 10}, Changes{})).
+		Set(BuildsStage, planner(Changes{Builds: 20}, Changes{Builds: 1})).
+		Set(BuildLogsStage, planner(Changes{BuildLogs: 20}, Changes{})).
+		Set(CompletedStage, CompletedExecutor)
+	for ss.HasNext() {
+		shouldBe.True(ss.ExecuteNext(context.TODO()))
+	}
+	shouldBe.Equal(StatusCompleted, task.Status)
+	shouldBe.Equal(StepWaiting, task.LastStep)
+	shouldBe.Empty(task.History)
+	shouldBe.Equal(&Plan{Changes: Changes{Releases: 10, Builds: 20, BuildLogs: 20}, Existing: Changes{Builds: 1}}, task.Plan)
+	shouldBe.True(task.Plan.HasConflicts())
+	mockedUpdater.AssertNumberOfCalls(t, "update", 4)
+}
+
+func TestStages_DryRun_Requeued(t *testing.T) {
+	shouldBe := assert.New(t)
+	mockedUpdater := &mockUpdater{}
+	mockedUpdater.On("update", mock.Anything, mock.Anything).Return(nil)
+	task := _WaitingTask()
+	task.DryRun = true
+	task.Plan = &Plan{Changes: Changes{Releases: 10}}
+	ss := NewStages(mockedUpdater.update, task).
+		Set(ReleasesStage, func(_ context.Context, task *Task) error {
+			task.Plan.Changes.Add(Changes{Releases: 10})
+			return nil
+		})
+	shouldBe.True(ss.ExecuteNext(context.TODO()))
+	shouldBe.Equal(&Plan{Changes: Changes{Releases: 10}}, task.Plan, "the plan of an earlier run is replaced")
+}
+
+func TestTask_ToMigration(t *testing.T) {
+	shouldBe := assert.New(t)
+	task := _WaitingTask()
+	task.DryRun = true
+	task.Plan = &Plan{Changes: Changes{Releases: 10}}
+	shouldBe.True(errors.Is(task.ToMigration(), ErrIllegalTransition), "dry-run isn't completed")
+	task.Status = StatusCompleted
+	task.Progress = &Progress{Stage: ReleasesStage}
+	shouldBe.Nil(task.ToMigration())
+	shouldBe.False(task.DryRun)
+	shouldBe.Equal(StatusQueued, task.Status)
+	shouldBe.Equal(StepWaiting, task.LastStep)
+	shouldBe.Nil(task.Progress)
+	shouldBe.Equal(&Plan{Changes: Changes{Releases: 10}}, task.Plan)
+	shouldBe.True(errors.Is(task.ToMigration(), ErrIllegalTransition), "task is no dry-run anymore")
+}
+
+func TestStages_DryRun_Failure(t *testing.T) {
+	shouldBe := assert.New(t)
+	mockedUpdater := &mockUpdater{}
+	mockedUpdater.On("update", mock.Anything, mock.Anything).Return(nil)
+	task := _WaitingTask()
+	task.DryRun = true
+	ss := NewStages(mockedUpdater.update, task).
+		Set(ReleasesStage, func(_ context.Context, _ *Task) error { return errors.New("test error") })
+	shouldBe.False(ss.ExecuteNext(context.TODO()))
+	shouldBe.Equal(StatusFailed, task.Status)
+	shouldBe.Equal(StepWaiting, task.LastStep)
+	if shouldBe.NotNil(task.ErrorDetails) {
+		shouldBe.Equal("test error", *task.ErrorDetails)
+	}
+	shouldBe.False(task.Plan.HasConflicts())
+}
+
+func TestRunner_Run_DryRun(t *testing.T) {
+	shouldBe := assert.New(t)
+	var callbacks atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		callbacks.Add(1)
+	}))
+	defer server.Close()
+	store := NewSQLStore(_SQLiteDB(t))
+	task := _WaitingTask()
+	task.ID = ""
+	task.DryRun = true
+	task.CallbackURL = &server.URL
+	shouldBe.Nil(store.Create(context.TODO(), task))
+	planner := func(changes Changes) Executor {
+		return func(ctx context.Context, task *Task) error {
+			task.Plan.Changes.Add(changes)
+			return nil
+		}
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	runner := NewRunner(store, map[StageName]Executor{
+		ReleasesStage:  planner(Changes{Releases: 10}),
+		BuildsStage:    planner(Changes{Builds: 20}),
+		CallbackStage:  CallbackExecutor,
+		CompletedStage: CompletedExecutor,
+	}, RunnerConfig{PollInterval: 10 * time.Millisecond})
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+	shouldBe.Eventually(func() bool {
+		stored, err := store.Get(context.TODO(), task.ID)
+		return err == nil && stored.Status == StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	shouldBe.Nil(<-done)
+	stored, err := store.Get(context.TODO(), task.ID)
+	if shouldBe.Nil(err) {
+		shouldBe.True(stored.DryRun)
+		shouldBe.Equal(StepWaiting, stored.LastStep)
+		shouldBe.Equal(&Plan{Changes: Changes{Releases: 10, Builds: 20}}, stored.Plan)
+	}
+	shouldBe.Equal(int32(0), callbacks.Load(), "a dry-run doesn't call the callback")
+}
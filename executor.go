@@ -11,26 +11,33 @@ import (
 // Executor is a function that executes a migration task and returns any changes and if it succeeded.
 type Executor func(ctx context.Context, task *Task) error
 
-// CallbackExecutor calls the callback URL if it's set.
-func CallbackExecutor(_ context.Context, task *Task) error {
-	if task.CallbackURL != nil {
-		payload, err := json.Marshal(task)
-		if err != nil {
-			return fmt.Errorf("failed to marshal migration callback payload: %w", err)
-		}
-		res, err := http.Post(*task.CallbackURL, "application/json", bytes.NewBuffer(payload))
-		if err != nil {
-			return fmt.Errorf("failed to httpPost migration callback: %w", err)
-		}
-		if _, err = _successful(res); err != nil {
-			return fmt.Errorf("migration callback: %w", err)
-		}
+// CallbackExecutor calls the callback URL if it's set, delivery results are recorded if ctx carries Metrics.
+// The callback isn't called for a dry-run, as receivers can't tell it from a finished migration, get the task for its Plan.
+func CallbackExecutor(ctx context.Context, task *Task) error {
+	if task.CallbackURL != nil && !task.DryRun {
+		err := callback(task)
+		MetricsFrom(ctx).observeCallback(err == nil)
+		return err
 	}
 	return nil
 }
 
-// CompletedExecutor set Task.Status to StatusCompleted.
-func CompletedExecutor(_ context.Context, task *Task) error {
-	task.Status = StatusCompleted
+func callback(task *Task) error {
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal migration callback payload: %w", err)
+	}
+	res, err := http.Post(*task.CallbackURL, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to httpPost migration callback: %w", err)
+	}
+	if _, err = _successful(res); err != nil {
+		return fmt.Errorf("migration callback: %w", err)
+	}
 	return nil
 }
+
+// CompletedExecutor set Task.Status to StatusCompleted, a completed dry-run keeps Task.DryRun set and holds the Plan.
+func CompletedExecutor(_ context.Context, task *Task) error {
+	return task.Transition(StatusCompleted, task.LastStep)
+}
@@ -2,6 +2,7 @@ package migration
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -47,3 +48,11 @@ func TestCompletedExecutor(t *testing.T) {
 	shouldBe.Nil(err)
 	shouldBe.Equal(StatusCompleted, task.Status)
 }
+
+func TestCompletedExecutor_Illegal(t *testing.T) {
+	shouldBe := assert.New(t)
+	task := &Task{Status: StatusFailed}
+	err := CompletedExecutor(context.TODO(), task)
+	shouldBe.True(errors.Is(err, ErrIllegalTransition))
+	shouldBe.Equal(StatusFailed, task.Status)
+}
@@ -0,0 +1,16 @@
+package migration
+
+import (
+	"time"
+)
+
+// HistoryEntry records an attempt to execute a stage of a task.
+type HistoryEntry struct {
+	Stage    StageName     `json:"stage"`
+	Step     Step          `json:"step"`
+	Status   Status        `json:"status"`
+	At       time.Time     `json:"at"`
+	Duration time.Duration `json:"duration"`
+	Error    *string       `json:"error,omitempty"`
+	WorkerID string        `json:"workerID,omitempty"`
+}
@@ -0,0 +1,96 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLeaseHeld = fmt.Errorf("migration task lease is held by another worker")
+	ErrLeaseLost = fmt.Errorf("migration task lease is no longer owned")
+)
+
+// Lease grants its owner the exclusive right to execute a task until it expires.
+type Lease struct {
+	TaskID    string    `json:"taskID"`
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Leaser manages task leases, so a task is executed by only one worker at a time.
+type Leaser interface {
+	// Acquire lease of the task for the owner, succeeds if there is no lease, it has expired or it is already owned by the owner.
+	// Returns ErrLeaseHeld if another owner holds an unexpired lease.
+	Acquire(ctx context.Context, taskID, owner string, ttl time.Duration) (*Lease, error)
+	// Renew extends the lease by ttl, returns ErrLeaseLost if the lease is no longer owned or expired.
+	Renew(ctx context.Context, lease *Lease, ttl time.Duration) error
+	// Release the lease if it is still owned.
+	Release(ctx context.Context, lease *Lease) error
+	// Expired returns expired leases, their tasks were abandoned by a crashed or partitioned worker.
+	Expired(ctx context.Context) ([]*Lease, error)
+}
+
+type memoryLeaser struct {
+	mu     sync.Mutex
+	leases map[string]Lease
+	now    func() time.Time
+}
+
+// NewMemoryLeaser returns a Leaser which keeps leases in memory, useful for a single process or tests.
+func NewMemoryLeaser() Leaser {
+	return &memoryLeaser{
+		leases: map[string]Lease{},
+		now:    time.Now,
+	}
+}
+
+func (ml *memoryLeaser) Acquire(_ context.Context, taskID, owner string, ttl time.Duration) (*Lease, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	now := ml.now()
+	if existing, ok := ml.leases[taskID]; ok && existing.Owner != owner && existing.ExpiresAt.After(now) {
+		return nil, ErrLeaseHeld
+	}
+	lease := Lease{TaskID: taskID, Owner: owner, ExpiresAt: now.Add(ttl)}
+	ml.leases[taskID] = lease
+	return &lease, nil
+}
+
+func (ml *memoryLeaser) Renew(_ context.Context, lease *Lease, ttl time.Duration) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	now := ml.now()
+	existing, ok := ml.leases[lease.TaskID]
+	if !ok || existing.Owner != lease.Owner || !existing.ExpiresAt.After(now) {
+		return ErrLeaseLost
+	}
+	existing.ExpiresAt = now.Add(ttl)
+	ml.leases[lease.TaskID] = existing
+	lease.ExpiresAt = existing.ExpiresAt
+	return nil
+}
+
+func (ml *memoryLeaser) Release(_ context.Context, lease *Lease) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if existing, ok := ml.leases[lease.TaskID]; ok && existing.Owner == lease.Owner {
+		delete(ml.leases, lease.TaskID)
+	}
+	return nil
+}
+
+func (ml *memoryLeaser) Expired(_ context.Context) ([]*Lease, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	now := ml.now()
+	expired := make([]*Lease, 0)
+	for _, existing := range ml.leases {
+		if !existing.ExpiresAt.After(now) {
+			lease := existing
+			expired = append(expired, &lease)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,60 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeasers(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	memory := NewMemoryLeaser().(*memoryLeaser)
+	memory.now = clock
+	sqlLeaser := NewSQLLeaser(_SQLiteDB(t))
+	sqlLeaser.now = clock
+	for name, leaser := range map[string]Leaser{"memory": memory, "sql": sqlLeaser} {
+		t.Run(name, func(t *testing.T) {
+			shouldBe := assert.New(t)
+			ctx := context.TODO()
+			now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			lease, err := leaser.Acquire(ctx, "task-1", "worker-1", time.Minute)
+			if !shouldBe.Nil(err) {
+				return
+			}
+			shouldBe.Equal(now.Add(time.Minute), lease.ExpiresAt)
+			_, err = leaser.Acquire(ctx, "task-1", "worker-1", time.Minute)
+			shouldBe.Nil(err, "owner can acquire its own lease again")
+			_, err = leaser.Acquire(ctx, "task-1", "worker-2", time.Minute)
+			shouldBe.True(errors.Is(err, ErrLeaseHeld))
+
+			now = now.Add(30 * time.Second)
+			shouldBe.Nil(leaser.Renew(ctx, lease, time.Minute))
+			shouldBe.Equal(now.Add(time.Minute), lease.ExpiresAt)
+			expired, err := leaser.Expired(ctx)
+			shouldBe.Nil(err)
+			shouldBe.Empty(expired)
+
+			now = now.Add(2 * time.Minute)
+			expired, err = leaser.Expired(ctx)
+			if shouldBe.Nil(err) && shouldBe.Len(expired, 1) {
+				shouldBe.Equal("task-1", expired[0].TaskID)
+				shouldBe.Equal("worker-1", expired[0].Owner)
+			}
+			shouldBe.True(errors.Is(leaser.Renew(ctx, lease, time.Minute), ErrLeaseLost), "expired lease can't be renewed")
+			stolen, err := leaser.Acquire(ctx, "task-1", "worker-2", time.Minute)
+			shouldBe.Nil(err, "expired lease can be acquired by another owner")
+			shouldBe.True(errors.Is(leaser.Renew(ctx, lease, time.Minute), ErrLeaseLost))
+
+			shouldBe.Nil(leaser.Release(ctx, lease), "releasing a lost lease is a no-op")
+			_, err = leaser.Acquire(ctx, "task-1", "worker-1", time.Minute)
+			shouldBe.True(errors.Is(err, ErrLeaseHeld))
+			shouldBe.Nil(leaser.Release(ctx, stolen))
+			_, err = leaser.Acquire(ctx, "task-1", "worker-1", time.Minute)
+			shouldBe.Nil(err)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package migration
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+const (
+	EntityBuild      EntityType = "build"
+	EntityRelease    EntityType = "release"
+	EntityBuildLog   EntityType = "build_log"
+	EntityReleaseLog EntityType = "release_log"
+)
+
+// EntityType of the rows mapped by a Change in a Ledger.
+type EntityType string
+
+// Ledger records the IDs of the rows created in the target pipeline by a migration task, mapped from the IDs of the source rows.
+type Ledger interface {
+	// Record changes of the task, recording a FromID again replaces its ToID so retried executors don't duplicate mappings.
+	Record(ctx context.Context, taskID string, entity EntityType, changes ...Change) error
+	// Changes of the task for the entity type ordered by FromID.
+	Changes(ctx context.Context, taskID string, entity EntityType) ([]Change, error)
+	// Clear all changes of the task, used after the migration is rolled back.
+	Clear(ctx context.Context, taskID string) error
+}
+
+// RecordChanges in the Ledger carried by ctx, it does nothing if there is no Ledger or the task is a dry-run.
+func RecordChanges(ctx context.Context, task *Task, entity EntityType, changes ...Change) error {
+	ledger := LedgerFrom(ctx)
+	if ledger == nil || task.DryRun || len(changes) == 0 {
+		return nil
+	}
+	return ledger.Record(ctx, task.ID, entity, changes...)
+}
+
+// MigratedIDs returns the target IDs by source ID of the entities already migrated by the task according to the Ledger
+// carried by ctx, executors skip them so a retried stage doesn't create duplicates. It is empty if there is no Ledger.
+func MigratedIDs(ctx context.Context, task *Task, entity EntityType) (map[int64]int64, error) {
+	migrated := map[int64]int64{}
+	ledger := LedgerFrom(ctx)
+	if ledger == nil {
+		return migrated, nil
+	}
+	changes, err := ledger.Changes(ctx, task.ID, entity)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		migrated[change.FromID] = change.ToID
+	}
+	return migrated, nil
+}
+
+type memoryLedger struct {
+	mu      sync.Mutex
+	changes map[string]map[EntityType]map[int64]int64
+}
+
+// NewMemoryLedger returns a Ledger which keeps changes in memory, useful for a single process or tests.
+func NewMemoryLedger() Ledger {
+	return &memoryLedger{
+		changes: map[string]map[EntityType]map[int64]int64{},
+	}
+}
+
+func (ml *memoryLedger) Record(_ context.Context, taskID string, entity EntityType, changes ...Change) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if ml.changes[taskID] == nil {
+		ml.changes[taskID] = map[EntityType]map[int64]int64{}
+	}
+	if ml.changes[taskID][entity] == nil {
+		ml.changes[taskID][entity] = map[int64]int64{}
+	}
+	for _, change := range changes {
+		ml.changes[taskID][entity][change.FromID] = change.ToID
+	}
+	return nil
+}
+
+func (ml *memoryLedger) Changes(_ context.Context, taskID string, entity EntityType) ([]Change, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	changes := make([]Change, 0, len(ml.changes[taskID][entity]))
+	for fromID, toID := range ml.changes[taskID][entity] {
+		changes = append(changes, Change{FromID: fromID, ToID: toID})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].FromID < changes[j].FromID
+	})
+	return changes, nil
+}
+
+func (ml *memoryLedger) Clear(_ context.Context, taskID string) error {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	delete(ml.changes, taskID)
+	return nil
+}
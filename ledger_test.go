@@ -0,0 +1,50 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedgers(t *testing.T) {
+	for name, ledger := range map[string]Ledger{"memory": NewMemoryLedger(), "sql": NewSQLLedger(_SQLiteDB(t))} {
+		t.Run(name, func(t *testing.T) {
+			shouldBe := assert.New(t)
+			ctx := context.TODO()
+			shouldBe.Nil(ledger.Record(ctx, "task-1", EntityBuild, Change{FromID: 2, ToID: 12}, Change{FromID: 1, ToID: 11}))
+			shouldBe.Nil(ledger.Record(ctx, "task-1", EntityBuild, Change{FromID: 2, ToID: 22}), "recording a FromID again replaces its ToID")
+			shouldBe.Nil(ledger.Record(ctx, "task-1", EntityRelease, Change{FromID: 5, ToID: 15}))
+			shouldBe.Nil(ledger.Record(ctx, "task-2", EntityBuild, Change{FromID: 1, ToID: 31}))
+
+			changes, err := ledger.Changes(ctx, "task-1", EntityBuild)
+			shouldBe.Nil(err)
+			shouldBe.Equal([]Change{{FromID: 1, ToID: 11}, {FromID: 2, ToID: 22}}, changes)
+			changes, err = ledger.Changes(ctx, "task-1", EntityBuildLog)
+			shouldBe.Nil(err)
+			shouldBe.Empty(changes)
+
+			shouldBe.Nil(ledger.Clear(ctx, "task-1"))
+			changes, err = ledger.Changes(ctx, "task-1", EntityRelease)
+			shouldBe.Nil(err)
+			shouldBe.Empty(changes)
+			changes, err = ledger.Changes(ctx, "task-2", EntityBuild)
+			shouldBe.Nil(err)
+			shouldBe.Equal([]Change{{FromID: 1, ToID: 31}}, changes)
+		})
+	}
+}
+
+func TestRecordChanges(t *testing.T) {
+	shouldBe := assert.New(t)
+	ledger := NewMemoryLedger()
+	task := &Task{Request: Request{ID: "task-1"}}
+	shouldBe.Nil(RecordChanges(context.TODO(), task, EntityBuild, Change{FromID: 1, ToID: 11}), "without ledger nothing is recorded")
+	ctx := ContextWithLedger(context.TODO(), ledger)
+	shouldBe.Nil(RecordChanges(ctx, task, EntityBuild, Change{FromID: 2, ToID: 12}))
+	task.DryRun = true
+	shouldBe.Nil(RecordChanges(ctx, task, EntityBuild, Change{FromID: 3, ToID: 13}))
+	changes, err := ledger.Changes(ctx, "task-1", EntityBuild)
+	shouldBe.Nil(err)
+	shouldBe.Equal([]Change{{FromID: 2, ToID: 12}}, changes)
+}
@@ -0,0 +1,222 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	contracts "github.com/estafette/estafette-ci-contracts"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrChecksumMismatch = fmt.Errorf("log checksum mismatch")
+
+// LogDigest of the steps of a log object as they are served, including their log lines with timestamps and every other
+// step field. The rest of the log object, its IDs, repository and insertion time, is not part of it as it differs between pipelines.
+type LogDigest struct {
+	// SHA256 of the JSON of the steps in order.
+	SHA256 string `json:"sha256,omitempty"`
+	// Size of the JSON of the steps in bytes.
+	Size int64 `json:"size"`
+}
+
+// ChecksumMismatch of the log object of a build or release, Change maps the ID in the source pipeline to the ID in the target pipeline.
+type ChecksumMismatch struct {
+	Type   LogType   `json:"type"`
+	Change Change    `json:"change"`
+	From   LogDigest `json:"from"`
+	To     LogDigest `json:"to"`
+	// Error retrieving the log of the source or target pipeline, like a missing log object.
+	Error string `json:"error,omitempty"`
+}
+
+func (m ChecksumMismatch) String() string {
+	if m.Error != "" {
+		return fmt.Sprintf("%s %d->%d: %s", m.Type, m.Change.FromID, m.Change.ToID, m.Error)
+	}
+	return fmt.Sprintf("%s %d->%d: %s (%d bytes) != %s (%d bytes)", m.Type, m.Change.FromID, m.Change.ToID, m.From.SHA256, m.From.Size, m.To.SHA256, m.To.Size)
+}
+
+// ChecksumReport of the log objects of the source and target pipeline of a Request.
+type ChecksumReport struct {
+	// Verified number of log objects with equal digests.
+	Verified   int                `json:"verified"`
+	Mismatches []ChecksumMismatch `json:"mismatches,omitempty"`
+}
+
+// Err returns ErrChecksumMismatch including the first mismatches or nil if all log objects are equal.
+func (r *ChecksumReport) Err() error {
+	if len(r.Mismatches) == 0 {
+		return nil
+	}
+	mismatches := make([]string, 0, maxReportedDifferences)
+	for index, mismatch := range r.Mismatches {
+		if index == maxReportedDifferences {
+			mismatches = append(mismatches, fmt.Sprintf("and %d more", len(r.Mismatches)-maxReportedDifferences))
+			break
+		}
+		mismatches = append(mismatches, mismatch.String())
+	}
+	return fmt.Errorf("%w: %d of %d log objects differ: %s", ErrChecksumMismatch, len(r.Mismatches), len(r.Mismatches)+r.Verified, strings.Join(mismatches, ", "))
+}
+
+// VerifyChecksums streams the steps of the log objects of every build or release of the given log types, defaulting to both, from the
+// source and target pipeline and compares their digests. Builds and releases are paired using the ledger or else like
+// in Verify, the ones missing in either pipeline are reported by Verify and skipped here.
+func (v *Verifier) VerifyChecksums(ctx context.Context, request *Request, logTypes ...LogType) (*ChecksumReport, error) {
+	if len(logTypes) == 0 {
+		logTypes = []LogType{BuildLog, ReleaseLog}
+	}
+	changes := make(map[LogType][]Change, len(logTypes))
+	total := 0
+	// the progress counts compared log objects, listing the builds and releases to pair them isn't reported
+	listCtx := contextWithProgress(ctx, nil)
+	for _, logType := range logTypes {
+		var err error
+		if changes[logType], err = v.changes(listCtx, request, logType); err != nil {
+			return nil, fmt.Errorf("verifying %s log checksums of %s: %w", logType, request.FromFQN(), err)
+		}
+		total += len(changes[logType])
+	}
+	ProgressFrom(ctx).SetTotal(ctx, int64(total))
+	report := &ChecksumReport{}
+	for _, logType := range logTypes {
+		for _, change := range changes[logType] {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			from, err := v.digest(logType, request.FromSource, request.FromOwner, request.FromName, change.FromID)
+			if err != nil {
+				report.Mismatches = append(report.Mismatches, ChecksumMismatch{Type: logType, Change: change, Error: fmt.Sprintf("source: %s", err)})
+				ProgressFrom(ctx).Add(ctx, 1, fmt.Sprintf("%s %d", logType, change.FromID))
+				continue
+			}
+			to, err := v.digest(logType, request.ToSource, request.ToOwner, request.ToName, change.ToID)
+			switch {
+			case err != nil:
+				report.Mismatches = append(report.Mismatches, ChecksumMismatch{Type: logType, Change: change, From: from, Error: fmt.Sprintf("target: %s", err)})
+			case from != to:
+				report.Mismatches = append(report.Mismatches, ChecksumMismatch{Type: logType, Change: change, From: from, To: to})
+			default:
+				report.Verified++
+			}
+			ProgressFrom(ctx).Add(ctx, 1, fmt.Sprintf("%s %d", logType, change.FromID))
+		}
+	}
+	return report, nil
+}
+
+// ChecksumExecutor verifies the log checksums of the task and fails if any log object differs, use it for LogChecksumsStage.
+// A dry-run is not verified as nothing is migrated.
+func (v *Verifier) ChecksumExecutor(logTypes ...LogType) Executor {
+	return func(ctx context.Context, task *Task) error {
+		if task.DryRun {
+			return nil
+		}
+		report, err := v.VerifyChecksums(ctx, &task.Request, logTypes...)
+		if err != nil {
+			return err
+		}
+		log.Info().Str("module", "github.com/estafette/migration").Str("taskID", task.ID).Int("verified", report.Verified).Int("mismatches", len(report.Mismatches)).Msg("verified log checksums")
+		return report.Err()
+	}
+}
+
+// changes pairs the IDs of the builds or releases of the source and target pipeline by their key.
+// The changes in the ledger of the Verifier are used if there are any.
+func (v *Verifier) changes(ctx context.Context, request *Request, logType LogType) ([]Change, error) {
+	if !logType.IsValid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownLogType, logType)
+	}
+	if v.ledger != nil && request.ID != "" {
+		entity := EntityBuild
+		if logType == ReleaseLog {
+			entity = EntityRelease
+		}
+		changes, err := v.ledger.Changes(ctx, request.ID, entity)
+		if err != nil || len(changes) > 0 {
+			return changes, err
+		}
+	}
+	var fromIDs, toIDs map[string]string
+	switch logType {
+	case BuildLog:
+		from, err := v.builds(ctx, request.FromSource, request.FromOwner, request.FromName)
+		if err != nil {
+			return nil, err
+		}
+		to, err := v.builds(ctx, request.ToSource, request.ToOwner, request.ToName)
+		if err != nil {
+			return nil, err
+		}
+		fromIDs, toIDs = _buildIDs(from), _buildIDs(to)
+	case ReleaseLog:
+		from, err := v.releases(ctx, request.FromSource, request.FromOwner, request.FromName)
+		if err != nil {
+			return nil, err
+		}
+		to, err := v.releases(ctx, request.ToSource, request.ToOwner, request.ToName)
+		if err != nil {
+			return nil, err
+		}
+		fromIDs, toIDs = _releaseIDs(from), _releaseIDs(to)
+	}
+	changes := make([]Change, 0, len(fromIDs))
+	for key, fromID := range fromIDs {
+		toID, ok := toIDs[key]
+		if !ok {
+			continue
+		}
+		change := Change{}
+		var err error
+		if change.FromID, err = strconv.ParseInt(fromID, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid ID %q of %s %s: %w", fromID, logType, key, err)
+		}
+		if change.ToID, err = strconv.ParseInt(toID, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid ID %q of %s %s: %w", toID, logType, key, err)
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].FromID < changes[j].FromID
+	})
+	return changes, nil
+}
+
+// digest streams the steps of the log object and returns their digest.
+func (v *Verifier) digest(logType LogType, source, owner, name string, id int64) (LogDigest, error) {
+	hash := sha256.New()
+	var size int64
+	err := v.client.StreamRawLogs(logType, source, owner, name, strconv.FormatInt(id, 10), func(step json.RawMessage) error {
+		n, err := hash.Write(step)
+		size += int64(n)
+		return err
+	})
+	if err != nil {
+		return LogDigest{}, err
+	}
+	return LogDigest{SHA256: hex.EncodeToString(hash.Sum(nil)), Size: size}, nil
+}
+
+// _buildIDs by key of the builds.
+func _buildIDs(builds []*contracts.Build) map[string]string {
+	ids := make(map[string]string, len(builds))
+	for index, key := range _buildKeys(builds) {
+		ids[key] = builds[index].ID
+	}
+	return ids
+}
+
+// _releaseIDs by key of the releases.
+func _releaseIDs(releases []*contracts.Release) map[string]string {
+	ids := make(map[string]string, len(releases))
+	for index, key := range _releaseKeys(releases) {
+		ids[key] = releases[index].ID
+	}
+	return ids
+}
@@ -0,0 +1,173 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	contracts "github.com/estafette/estafette-ci-contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// logClient serves the pipelines of pipelineClient and log steps by source, log type and ID.
+type logClient struct {
+	pipelineClient
+	logs map[string][]*contracts.BuildLogStep
+}
+
+func (c *logClient) StreamRawLogs(logType LogType, source, _, _, id string, fn func(step json.RawMessage) error) error {
+	steps, ok := c.logs[source+"/"+string(logType)+"/"+id]
+	if !ok {
+		return fmt.Errorf("responded with status: 404 Not Found")
+	}
+	for _, step := range steps {
+		raw, err := json.Marshal(step)
+		if err != nil {
+			return err
+		}
+		if err = fn(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func _logStep(name string, lines ...string) *contracts.BuildLogStep {
+	step := &contracts.BuildLogStep{Step: name, Status: contracts.LogStatusSucceeded}
+	for index, line := range lines {
+		step.LogLines = append(step.LogLines, contracts.BuildLogLine{LineNumber: index + 1, StreamType: "stdout", Text: line, Timestamp: time.Date(2023, 1, 1, 0, 0, index, 0, time.UTC)})
+	}
+	return step
+}
+
+func TestVerifier_VerifyChecksums(t *testing.T) {
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	request := &Request{FromSource: "bitbucket.org", FromOwner: "owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "repo1"}
+	build := func(id, version string, started time.Time) *contracts.Build {
+		b := _verifierBuild(version, "abc", contracts.StatusSucceeded, started)
+		b.ID = id
+		return b
+	}
+	release := func(id string, started time.Time) *contracts.Release {
+		r := _verifierRelease("production", "1.0.0", contracts.StatusSucceeded, started)
+		r.ID = id
+		return r
+	}
+	client := &logClient{
+		pipelineClient: pipelineClient{
+			builds: map[string][]*contracts.Build{
+				"bitbucket.org": {build("1", "1.0.0", day), build("2", "1.0.1", day.Add(time.Hour)), build("3", "1.0.2", day.Add(2*time.Hour))},
+				"github.com":    {build("11", "1.0.0", day), build("12", "1.0.1", day.Add(time.Hour)), build("13", "1.0.2", day.Add(2*time.Hour))},
+			},
+			releases: map[string][]*contracts.Release{
+				"bitbucket.org": {release("5", day), release("6", day.Add(time.Hour))},
+				"github.com":    {release("15", day), release("16", day.Add(time.Hour))},
+			},
+		},
+		logs: map[string][]*contracts.BuildLogStep{
+			"bitbucket.org/builds/1":   {_logStep("build", "compiling", "done")},
+			"github.com/builds/11":     {_logStep("build", "compiling", "done")},
+			"bitbucket.org/builds/2":   {_logStep("build", "compiling", "done")},
+			"github.com/builds/12":     {_logStep("build", "compiling")},
+			"bitbucket.org/builds/3":   {_logStep("build")},
+			"bitbucket.org/releases/5": {_logStep("deploy", "deploying")},
+			"github.com/releases/15":   {_logStep("deploy", "deploying")},
+			"bitbucket.org/releases/6": {_logStep("deploy", "deploying")},
+			"github.com/releases/16":   {_logStep("deploy", "deploying")},
+		},
+	}
+	shouldBe := assert.New(t)
+	verifier := NewVerifier(client)
+	report, err := verifier.VerifyChecksums(context.Background(), request)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(3, report.Verified)
+		if shouldBe.Len(report.Mismatches, 2) {
+			shouldBe.Equal(BuildLog, report.Mismatches[0].Type)
+			shouldBe.Equal(Change{FromID: 2, ToID: 12}, report.Mismatches[0].Change)
+			shouldBe.NotEqual(report.Mismatches[0].From.SHA256, report.Mismatches[0].To.SHA256)
+			shouldBe.Greater(report.Mismatches[0].From.Size, report.Mismatches[0].To.Size)
+			shouldBe.Equal(Change{FromID: 3, ToID: 13}, report.Mismatches[1].Change)
+			shouldBe.Contains(report.Mismatches[1].Error, "404")
+		}
+		shouldBe.True(errors.Is(report.Err(), ErrChecksumMismatch))
+	}
+
+	report, err = verifier.VerifyChecksums(context.Background(), request, ReleaseLog)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(2, report.Verified)
+		shouldBe.Nil(report.Err())
+	}
+
+	ledger := NewMemoryLedger()
+	shouldBe.Nil(ledger.Record(context.Background(), "task-1", EntityBuild, Change{FromID: 1, ToID: 11}))
+	ledgerRequest := *request
+	ledgerRequest.ID = "task-1"
+	report, err = NewVerifierWithLedger(client, ledger).VerifyChecksums(context.Background(), &ledgerRequest, BuildLog)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(1, report.Verified, "only changes in the ledger are verified")
+		shouldBe.Empty(report.Mismatches)
+	}
+
+	task := &Task{Request: *request}
+	shouldBe.True(errors.Is(verifier.ChecksumExecutor(BuildLog)(context.Background(), task), ErrChecksumMismatch))
+	shouldBe.Nil(verifier.ChecksumExecutor(ReleaseLog)(context.Background(), task))
+}
+
+func TestVerifier_VerifyChecksums_StepFields(t *testing.T) {
+	shouldBe := assert.New(t)
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	request := &Request{FromSource: "bitbucket.org", FromOwner: "owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "repo1"}
+	build := func(id string) *contracts.Build {
+		b := _verifierBuild("1.0.0", "abc", contracts.StatusSucceeded, day)
+		b.ID = id
+		return b
+	}
+	from := _logStep("build", "compiling")
+	to := _logStep("build", "compiling")
+	to.LogLines[0].Timestamp = to.LogLines[0].Timestamp.Add(time.Second)
+	client := &logClient{
+		pipelineClient: pipelineClient{builds: map[string][]*contracts.Build{"bitbucket.org": {build("1")}, "github.com": {build("11")}}},
+		logs:           map[string][]*contracts.BuildLogStep{"bitbucket.org/builds/1": {from}, "github.com/builds/11": {to}},
+	}
+	report, err := NewVerifier(client).VerifyChecksums(context.Background(), request, BuildLog)
+	if shouldBe.Nil(err) && shouldBe.Len(report.Mismatches, 1, "a different line timestamp is a mismatch") {
+		raw, _ := json.Marshal(from)
+		shouldBe.Equal(int64(len(raw)), report.Mismatches[0].From.Size)
+		shouldBe.NotEqual(report.Mismatches[0].From.SHA256, report.Mismatches[0].To.SHA256)
+	}
+}
+
+func TestVerifier_VerifyChecksums_MissingSource(t *testing.T) {
+	shouldBe := assert.New(t)
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	request := &Request{FromSource: "bitbucket.org", FromOwner: "owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "repo1"}
+	build := func(id, version string) *contracts.Build {
+		b := _verifierBuild(version, "abc", contracts.StatusSucceeded, day)
+		b.ID = id
+		return b
+	}
+	client := &logClient{
+		pipelineClient: pipelineClient{builds: map[string][]*contracts.Build{
+			"bitbucket.org": {build("1", "1.0.0"), build("2", "1.0.1")},
+			"github.com":    {build("11", "1.0.0"), build("12", "1.0.1")},
+		}},
+		logs: map[string][]*contracts.BuildLogStep{"bitbucket.org/builds/2": {_logStep("build")}, "github.com/builds/12": {_logStep("build")}},
+	}
+	task := &Task{Request: *request}
+	ctx := contextWithProgress(context.Background(), newProgressReporter(LogChecksumsStage, task, func(context.Context, *Task) error { return nil }, 0))
+	report, err := NewVerifier(client).VerifyChecksums(ctx, request, BuildLog)
+	if shouldBe.Nil(err, "a missing source log doesn't stop the verification") {
+		shouldBe.Equal(1, report.Verified)
+		if shouldBe.Len(report.Mismatches, 1) {
+			shouldBe.Equal(Change{FromID: 1, ToID: 11}, report.Mismatches[0].Change)
+			shouldBe.Contains(report.Mismatches[0].Error, "source")
+		}
+	}
+	if shouldBe.NotNil(task.Progress) {
+		shouldBe.Equal(int64(2), task.Progress.Total)
+		shouldBe.Equal(int64(2), task.Progress.Processed, "listing builds isn't counted as progress")
+	}
+}
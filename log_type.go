@@ -1,8 +1,33 @@
 package migration
 
+import (
+	"fmt"
+	"time"
+
+	contracts "github.com/estafette/estafette-ci-contracts"
+)
+
 const (
 	BuildLog   LogType = "builds"
 	ReleaseLog LogType = "releases"
 )
 
+var ErrUnknownLogType = fmt.Errorf("unknown log type")
+
+// LogType of build or release logs, it's the path segment of builds or releases in the pipelines API.
 type LogType string
+
+// IsValid returns true for BuildLog and ReleaseLog.
+func (lt LogType) IsValid() bool {
+	return lt == BuildLog || lt == ReleaseLog
+}
+
+// Log of a build or release.
+type Log struct {
+	ID   string  `json:"id,omitempty"`
+	Type LogType `json:"type"`
+	// ParentID is the ID of the build or release.
+	ParentID   string                    `json:"parentID"`
+	Steps      []*contracts.BuildLogStep `json:"steps"`
+	InsertedAt time.Time                 `json:"insertedAt"`
+}
@@ -0,0 +1,151 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MappingExact replaces the value if it equals From.
+	MappingExact MappingType = "exact"
+	// MappingLookup replaces the value by its entry in Lookup if it has one, all entries are a single lookup so they don't chain.
+	MappingLookup MappingType = "lookup"
+	// MappingPrefix replaces the prefix From of the value by To, an empty From prefixes all values with To.
+	MappingPrefix MappingType = "prefix"
+	// MappingRegex replaces matches of the regular expression From by To, which can refer to capture groups like ${1}.
+	MappingRegex MappingType = "regex"
+	// MappingLowercase converts the value to lowercase.
+	MappingLowercase MappingType = "lowercase"
+	// MappingSlug converts the value to lowercase and replaces runs of characters other than a-z, 0-9, '.', '_' and '-' by '-'.
+	MappingSlug MappingType = "slug"
+)
+
+const (
+	MappingSource MappingField = "source"
+	MappingOwner  MappingField = "owner"
+	MappingName   MappingField = "name"
+)
+
+var (
+	ErrInvalidMapping = fmt.Errorf("invalid mapping rule")
+	slugInvalid       = regexp.MustCompile(`[^a-z0-9._-]+`)
+)
+
+type MappingType string
+
+type MappingField string
+
+// MappingRule rewrites a field of the source repository to derive the target repository.
+type MappingRule struct {
+	Field MappingField `yaml:"field" json:"field"`
+	Type  MappingType  `yaml:"type" json:"type"`
+	From  string       `yaml:"from,omitempty" json:"from,omitempty"`
+	To    string       `yaml:"to,omitempty" json:"to,omitempty"`
+	// Lookup maps values to their replacement for MappingLookup.
+	Lookup map[string]string `yaml:"lookup,omitempty" json:"lookup,omitempty"`
+	regex  *regexp.Regexp
+}
+
+// Mapping derives ToSource, ToOwner and ToName from FromSource, FromOwner and FromName using rules applied in order.
+type Mapping struct {
+	rules []MappingRule
+}
+
+// NewMapping validates the rules and returns a Mapping applying them in order.
+func NewMapping(rules ...MappingRule) (*Mapping, error) {
+	var errs []error
+	compiled := make([]MappingRule, 0, len(rules))
+	for index, rule := range rules {
+		if err := rule.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", index, err))
+			continue
+		}
+		compiled = append(compiled, rule)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &Mapping{rules: compiled}, nil
+}
+
+func (mr *MappingRule) compile() error {
+	switch mr.Field {
+	case MappingSource, MappingOwner, MappingName:
+	default:
+		return fmt.Errorf("%w: unknown field %q", ErrInvalidMapping, mr.Field)
+	}
+	switch mr.Type {
+	case MappingExact:
+		if mr.From == "" || mr.To == "" {
+			return fmt.Errorf("%w: exact mapping requires from and to", ErrInvalidMapping)
+		}
+	case MappingLookup:
+		if len(mr.Lookup) == 0 {
+			return fmt.Errorf("%w: lookup mapping requires lookup", ErrInvalidMapping)
+		}
+	case MappingPrefix:
+		if mr.From == "" && mr.To == "" {
+			return fmt.Errorf("%w: prefix mapping requires from or to", ErrInvalidMapping)
+		}
+	case MappingRegex:
+		regex, err := regexp.Compile(mr.From)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidMapping, err)
+		}
+		mr.regex = regex
+	case MappingLowercase, MappingSlug:
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidMapping, mr.Type)
+	}
+	return nil
+}
+
+func (mr *MappingRule) apply(value string) string {
+	switch mr.Type {
+	case MappingExact:
+		if value == mr.From {
+			return mr.To
+		}
+	case MappingLookup:
+		if to, ok := mr.Lookup[value]; ok {
+			return to
+		}
+	case MappingPrefix:
+		if strings.HasPrefix(value, mr.From) {
+			return mr.To + strings.TrimPrefix(value, mr.From)
+		}
+	case MappingRegex:
+		return mr.regex.ReplaceAllString(value, mr.To)
+	case MappingLowercase:
+		return strings.ToLower(value)
+	case MappingSlug:
+		return strings.Trim(slugInvalid.ReplaceAllString(strings.ToLower(value), "-"), "-")
+	}
+	return value
+}
+
+// Map the value of the field using all rules of the field in order.
+func (m *Mapping) Map(field MappingField, value string) string {
+	for index := range m.rules {
+		if m.rules[index].Field == field {
+			value = m.rules[index].apply(value)
+		}
+	}
+	return value
+}
+
+// Apply the mapping to the request, target fields which are already set are kept.
+func (m *Mapping) Apply(request Request) Request {
+	if request.ToSource == "" {
+		request.ToSource = m.Map(MappingSource, request.FromSource)
+	}
+	if request.ToOwner == "" {
+		request.ToOwner = m.Map(MappingOwner, request.FromOwner)
+	}
+	if request.ToName == "" {
+		request.ToName = m.Map(MappingName, request.FromName)
+	}
+	return request
+}
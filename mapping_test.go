@@ -0,0 +1,82 @@
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapping_Map(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []MappingRule
+		field    MappingField
+		value    string
+		expected string
+	}{
+		{name: "exact", rules: []MappingRule{{Field: MappingOwner, Type: MappingExact, From: "old", To: "new"}}, field: MappingOwner, value: "old", expected: "new"},
+		{name: "exact_no_match", rules: []MappingRule{{Field: MappingOwner, Type: MappingExact, From: "old", To: "new"}}, field: MappingOwner, value: "older", expected: "older"},
+		{name: "other_field", rules: []MappingRule{{Field: MappingOwner, Type: MappingExact, From: "old", To: "new"}}, field: MappingName, value: "old", expected: "old"},
+		{name: "lookup", rules: []MappingRule{{Field: MappingOwner, Type: MappingLookup, Lookup: map[string]string{"a": "b", "b": "c"}}}, field: MappingOwner, value: "a", expected: "b"},
+		{name: "lookup_swap", rules: []MappingRule{{Field: MappingOwner, Type: MappingLookup, Lookup: map[string]string{"a": "b", "b": "a"}}}, field: MappingOwner, value: "b", expected: "a"},
+		{name: "lookup_no_match", rules: []MappingRule{{Field: MappingOwner, Type: MappingLookup, Lookup: map[string]string{"a": "b"}}}, field: MappingOwner, value: "c", expected: "c"},
+		{name: "prefix_replace", rules: []MappingRule{{Field: MappingName, Type: MappingPrefix, From: "bb-", To: "gh-"}}, field: MappingName, value: "bb-repo", expected: "gh-repo"},
+		{name: "prefix_add", rules: []MappingRule{{Field: MappingName, Type: MappingPrefix, To: "team-"}}, field: MappingName, value: "repo", expected: "team-repo"},
+		{name: "regex", rules: []MappingRule{{Field: MappingName, Type: MappingRegex, From: `^(\w+)-service$`, To: "svc-${1}"}}, field: MappingName, value: "payments-service", expected: "svc-payments"},
+		{name: "lowercase", rules: []MappingRule{{Field: MappingOwner, Type: MappingLowercase}}, field: MappingOwner, value: "MyTeam", expected: "myteam"},
+		{name: "slug", rules: []MappingRule{{Field: MappingName, Type: MappingSlug}}, field: MappingName, value: " My Repo (Legacy)! ", expected: "my-repo-legacy"},
+		{name: "ordered", rules: []MappingRule{
+			{Field: MappingName, Type: MappingSlug},
+			{Field: MappingName, Type: MappingPrefix, To: "team-"},
+			{Field: MappingName, Type: MappingExact, From: "team-legacy", To: "legacy"},
+		}, field: MappingName, value: "Legacy", expected: "legacy"},
+		{name: "source", rules: []MappingRule{{Field: MappingSource, Type: MappingExact, From: "bitbucket.org", To: "github.com"}}, field: MappingSource, value: "bitbucket.org", expected: "github.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := NewMapping(tt.rules...)
+			if assert.Nil(t, err) {
+				assert.Equal(t, tt.expected, mapping.Map(tt.field, tt.value))
+			}
+		})
+	}
+}
+
+func TestMapping_Apply(t *testing.T) {
+	shouldBe := assert.New(t)
+	mapping, err := NewMapping(
+		MappingRule{Field: MappingSource, Type: MappingExact, From: "bitbucket.org", To: "github.com"},
+		MappingRule{Field: MappingOwner, Type: MappingLowercase},
+		MappingRule{Field: MappingName, Type: MappingPrefix, To: "bb-"},
+	)
+	if !shouldBe.Nil(err) {
+		return
+	}
+	shouldBe.Equal(
+		Request{FromSource: "bitbucket.org", FromOwner: "Owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "bb-repo1"},
+		mapping.Apply(Request{FromSource: "bitbucket.org", FromOwner: "Owner1", FromName: "repo1"}),
+	)
+	shouldBe.Equal(
+		Request{FromSource: "bitbucket.org", FromOwner: "Owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "kept"},
+		mapping.Apply(Request{FromSource: "bitbucket.org", FromOwner: "Owner1", FromName: "repo1", ToName: "kept"}),
+	)
+}
+
+func TestNewMapping_Invalid(t *testing.T) {
+	shouldBe := assert.New(t)
+	mapping, err := NewMapping(
+		MappingRule{Field: "branch", Type: MappingLowercase},
+		MappingRule{Field: MappingName, Type: "upper"},
+		MappingRule{Field: MappingName, Type: MappingRegex, From: "("},
+		MappingRule{Field: MappingName, Type: MappingExact, From: "only-from"},
+		MappingRule{Field: MappingName, Type: MappingLookup},
+	)
+	shouldBe.Nil(mapping)
+	shouldBe.True(errors.Is(err, ErrInvalidMapping))
+	shouldBe.ErrorContains(err, `rule 0: invalid mapping rule: unknown field "branch"`)
+	shouldBe.ErrorContains(err, `rule 1: invalid mapping rule: unknown type "upper"`)
+	shouldBe.ErrorContains(err, `rule 2: invalid mapping rule: error parsing regexp`)
+	shouldBe.ErrorContains(err, `rule 3: invalid mapping rule: exact mapping requires from and to`)
+	shouldBe.ErrorContains(err, `rule 4: invalid mapping rule: lookup mapping requires lookup`)
+}
@@ -0,0 +1,124 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "estafette_migration"
+
+// Metrics collects prometheus metrics for stages, tasks, callbacks and client requests.
+// All methods are safe to call on a nil *Metrics, which makes metrics optional for consumers.
+type Metrics struct {
+	stageDuration   *prometheus.HistogramVec
+	stages          *prometheus.CounterVec
+	tasks           *prometheus.CounterVec
+	taskStatus      *prometheus.GaugeVec
+	callbacks       *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+// NewMetrics creates Metrics and registers its collectors on the given registerer.
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "stage_duration_seconds",
+			Help:      "Duration of migration stages by stage name and result.",
+			Buckets:   []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200, 14400},
+		}, []string{"stage", "result"}),
+		stages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "stages_total",
+			Help:      "Number of executed migration stages by stage name and result.",
+		}, []string{"stage", "result"}),
+		tasks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "tasks_total",
+			Help:      "Number of migration tasks that moved into a status while executing a stage.",
+		}, []string{"status"}),
+		taskStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "tasks",
+			Help:      "Number of migration tasks by status, including tasks created, claimed or queued again outside of stages.",
+		}, []string{"status"}),
+		callbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "callbacks_total",
+			Help:      "Number of migration callback deliveries by result.",
+		}, []string{"result"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "client_request_duration_seconds",
+			Help:      "Duration of estafette-ci-api requests by endpoint and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint", "code"}),
+	}
+	for _, collector := range []prometheus.Collector{m.stageDuration, m.stages, m.tasks, m.taskStatus, m.callbacks, m.requestDuration} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, fmt.Errorf("error while registering metrics: %w", err)
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) observeStage(name StageName, succeeded bool, took time.Duration) {
+	if m == nil {
+		return
+	}
+	result := _result(succeeded)
+	m.stageDuration.WithLabelValues(string(name), result).Observe(took.Seconds())
+	m.stages.WithLabelValues(string(name), result).Inc()
+}
+
+func (m *Metrics) observeTask(status Status) {
+	if m == nil {
+		return
+	}
+	m.tasks.WithLabelValues(status.String()).Inc()
+}
+
+// observeTasks sets the number of tasks by status to the tasks listed from the source.
+func (m *Metrics) observeTasks(ctx context.Context, source TaskSource) error {
+	if m == nil {
+		return nil
+	}
+	for _, status := range []Status{StatusQueued, StatusInProgress, StatusFailed, StatusCompleted, StatusCanceled} {
+		tasks, err := source.List(ctx, status)
+		if err != nil {
+			return fmt.Errorf("error while listing %s tasks: %w", status.String(), err)
+		}
+		m.taskStatus.WithLabelValues(status.String()).Set(float64(len(tasks)))
+	}
+	return nil
+}
+
+func (m *Metrics) observeCallback(delivered bool) {
+	if m == nil {
+		return
+	}
+	m.callbacks.WithLabelValues(_result(delivered)).Inc()
+}
+
+// observeRequest records the duration of a request, statusCode 0 is used when no response was received.
+func (m *Metrics) observeRequest(endpoint string, statusCode int, took time.Duration) {
+	if m == nil {
+		return
+	}
+	code := "error"
+	if statusCode != 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	m.requestDuration.WithLabelValues(endpoint, code).Observe(took.Seconds())
+}
+
+func _result(succeeded bool) string {
+	if succeeded {
+		return "success"
+	}
+	return "failure"
+}
@@ -0,0 +1,102 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewMetrics(t *testing.T) {
+	shouldBe := assert.New(t)
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(registry)
+	shouldBe.Nil(err)
+	shouldBe.NotNil(metrics)
+	_, err = NewMetrics(registry)
+	shouldBe.NotNil(err)
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var metrics *Metrics
+	assert.NotPanics(t, func() {
+		metrics.observeStage(BuildsStage, true, 0)
+		metrics.observeTask(StatusCompleted)
+		metrics.observeCallback(true)
+		_ = metrics.observeTasks(context.TODO(), nil)
+		metrics.observeRequest("queue", 200, 0)
+	})
+}
+
+func TestMetrics_Stages(t *testing.T) {
+	shouldBe := assert.New(t)
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	shouldBe.Nil(err)
+	mockedUpdater := &mockUpdater{}
+	mockedUpdater.On("update", mock.Anything, mock.Anything).Return(nil)
+	mockedExecutor := &mockExecutor{}
+	mockedExecutor.On("execute", mock.Anything, mock.Anything).Return(nil).Once()
+	mockedExecutor.On("execute", mock.Anything, mock.Anything).Return(errors.New("test error")).Once()
+	ss := NewStages(mockedUpdater.update, _WaitingTask()).
+		Set(ReleasesStage, mockedExecutor.execute).
+		Set(BuildsStage, mockedExecutor.execute)
+	ctx := ContextWithMetrics(context.TODO(), metrics)
+	shouldBe.True(ss.ExecuteNext(ctx))
+	shouldBe.False(ss.ExecuteNext(ctx))
+	shouldBe.Equal(1.0, testutil.ToFloat64(metrics.stages.WithLabelValues(string(ReleasesStage), "success")))
+	shouldBe.Equal(1.0, testutil.ToFloat64(metrics.stages.WithLabelValues(string(BuildsStage), "failure")))
+	shouldBe.Equal(1.0, testutil.ToFloat64(metrics.tasks.WithLabelValues("failed")))
+	shouldBe.Equal(2, testutil.CollectAndCount(metrics.stageDuration))
+}
+
+func TestMetrics_Tasks(t *testing.T) {
+	shouldBe := assert.New(t)
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	shouldBe.Nil(err)
+	store := NewSQLStore(_SQLiteDB(t))
+	for i := 0; i < 3; i++ {
+		task := _WaitingTask()
+		task.ID = ""
+		shouldBe.Nil(store.Create(context.TODO(), task))
+	}
+	_, err = store.ClaimNext(context.TODO())
+	shouldBe.Nil(err)
+	shouldBe.Nil(metrics.observeTasks(context.TODO(), store))
+	shouldBe.Equal(2.0, testutil.ToFloat64(metrics.taskStatus.WithLabelValues("queued")), "tasks created outside of stages")
+	shouldBe.Equal(1.0, testutil.ToFloat64(metrics.taskStatus.WithLabelValues("in_progress")), "tasks claimed outside of stages")
+	shouldBe.Equal(0.0, testutil.ToFloat64(metrics.taskStatus.WithLabelValues("completed")))
+}
+
+func TestMetrics_Client(t *testing.T) {
+	shouldBe := assert.New(t)
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	shouldBe.Nil(err)
+	mockedClient := &mockClient{}
+	c := &client{
+		httpClient: mockedClient,
+		bearerAuth: bearerAuth{
+			clientID:     "test-clientID",
+			clientSecret: "test-clientSecret",
+		},
+		serverURL: "http://localhost:80",
+		metrics:   metrics,
+	}
+	mockAuth(mockedClient).Once()
+	mockedClient.On("Do", mock.Anything).
+		Return(&http.Response{Status: "404 Not Found", StatusCode: 404, Body: io.NopCloser(strings.NewReader(`{"code":404,"message":"migration task not found"}`))}, nil).
+		Once()
+	_, err = c.GetMigrationByID("test-123")
+	shouldBe.NotNil(err)
+	shouldBe.Equal(2, testutil.CollectAndCount(metrics.requestDuration))
+	observed := &dto.Metric{}
+	shouldBe.Nil(metrics.requestDuration.WithLabelValues("getMigrationByID", "404").(prometheus.Histogram).Write(observed))
+	shouldBe.Equal(uint64(1), observed.GetHistogram().GetSampleCount())
+}
@@ -1,8 +1,20 @@
 package migration
 
-import contracts "github.com/estafette/estafette-ci-contracts"
+import (
+	"time"
+
+	contracts "github.com/estafette/estafette-ci-contracts"
+)
 
 type PagedBuildsResponse struct {
 	Items      []*contracts.Build   `json:"items"`
 	Pagination contracts.Pagination `json:"pagination"`
 }
+
+// _buildTime is when the build started, or was inserted if it is still pending.
+func _buildTime(build *contracts.Build) time.Time {
+	if build.StartedAt != nil {
+		return *build.StartedAt
+	}
+	return build.InsertedAt
+}
@@ -0,0 +1,23 @@
+package migration
+
+import (
+	"time"
+
+	contracts "github.com/estafette/estafette-ci-contracts"
+)
+
+type PagedReleasesResponse struct {
+	Items      []*contracts.Release `json:"items"`
+	Pagination contracts.Pagination `json:"pagination"`
+}
+
+// _releaseTime is when the release started, or was inserted if it is still pending, zero if neither is known.
+func _releaseTime(release *contracts.Release) time.Time {
+	if release.StartedAt != nil {
+		return *release.StartedAt
+	}
+	if release.InsertedAt != nil {
+		return *release.InsertedAt
+	}
+	return time.Time{}
+}
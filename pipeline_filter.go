@@ -0,0 +1,84 @@
+package migration
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	contracts "github.com/estafette/estafette-ci-contracts"
+)
+
+// PipelineFilter selects a page of builds or releases of a pipeline, zero values don't filter.
+type PipelineFilter struct {
+	// Branch of builds, releases have no branch and ignore it.
+	Branch string
+	// Statuses of builds or releases, any of them matches.
+	Statuses []contracts.Status
+	// Since is the earliest start time, builds and releases that are pending use their insert time.
+	Since time.Time
+	// Until is the latest start time, builds and releases that are pending use their insert time.
+	Until time.Time
+	// Page number starting at 1, defaults to 1.
+	Page int
+	// Size of the page, defaults to 100.
+	Size int
+}
+
+// query parameters of the filter, the page defaults are applied.
+func (f PipelineFilter) query() url.Values {
+	query := url.Values{}
+	if f.Branch != "" {
+		query.Set("filter[branch]", f.Branch)
+	}
+	for _, status := range f.Statuses {
+		query.Add("filter[status]", string(status))
+	}
+	if !f.Since.IsZero() {
+		query.Set("filter[since]", f.Since.UTC().Format(time.RFC3339))
+	}
+	if !f.Until.IsZero() {
+		query.Set("filter[until]", f.Until.UTC().Format(time.RFC3339))
+	}
+	page, size := f.Page, f.Size
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = pageSize
+	}
+	query.Set("page[number]", strconv.Itoa(page))
+	query.Set("page[size]", strconv.Itoa(size))
+	return query
+}
+
+// matchesBuild is used to filter again on the client, older servers ignore some of the filters.
+func (f PipelineFilter) matchesBuild(build *contracts.Build) bool {
+	if build == nil || (f.Branch != "" && build.RepoBranch != f.Branch) {
+		return false
+	}
+	return f.matches(build.BuildStatus, _buildTime(build))
+}
+
+// matchesRelease is used to filter again on the client, older servers ignore some of the filters.
+func (f PipelineFilter) matchesRelease(release *contracts.Release) bool {
+	if release == nil {
+		return false
+	}
+	return f.matches(release.ReleaseStatus, _releaseTime(release))
+}
+
+func (f PipelineFilter) matches(status contracts.Status, at time.Time) bool {
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, s := range f.Statuses {
+			found = found || s == status
+		}
+		if !found {
+			return false
+		}
+	}
+	if at.IsZero() {
+		return true
+	}
+	return (f.Since.IsZero() || !at.Before(f.Since)) && (f.Until.IsZero() || !at.After(f.Until))
+}
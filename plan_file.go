@@ -0,0 +1,172 @@
+package migration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PlanFile describes migrations of many repositories, it's written in YAML or JSON so bulk migrations can be reviewed in git.
+//
+// Example:
+//
+//	defaults:
+//	  callbackURL: https://your-callback-url
+//	sources:
+//	  bitbucket.org: github.com
+//	owners:
+//	  owner1: new-owner1
+//	rules:
+//	  - field: name
+//	    type: slug
+//	migrations:
+//	  - from: bitbucket.org/owner1/repo1
+//	  - from: bitbucket.org/owner2/repo2
+//	    to: github.com/owner2/new-repo2
+//	    restart: builds
+type PlanFile struct {
+	Defaults PlanDefaults `yaml:"defaults" json:"defaults"`
+	// Sources maps source hosts to target source hosts, used when a migration has no target. It is a single lookup,
+	// so entries don't chain and can swap hosts.
+	Sources map[string]string `yaml:"sources" json:"sources"`
+	// Owners maps source owners to target owners, used when a migration has no target. Like Sources it is a single lookup.
+	Owners map[string]string `yaml:"owners" json:"owners"`
+	// Rules are applied after Sources and Owners, used when a migration has no target.
+	Rules      []MappingRule   `yaml:"rules" json:"rules"`
+	Migrations []PlanMigration `yaml:"migrations" json:"migrations"`
+}
+
+// PlanDefaults apply to every migration of the PlanFile which doesn't set them.
+type PlanDefaults struct {
+	CallbackURL string    `yaml:"callbackURL" json:"callbackURL"`
+	Restart     StageName `yaml:"restart" json:"restart"`
+	DryRun      bool      `yaml:"dryRun" json:"dryRun"`
+}
+
+// PlanMigration of a repository, From and To are fully qualified names of the pipeline like github.com/owner/name.
+type PlanMigration struct {
+	ID          string    `yaml:"id" json:"id"`
+	From        string    `yaml:"from" json:"from"`
+	To          string    `yaml:"to" json:"to"`
+	CallbackURL string    `yaml:"callbackURL" json:"callbackURL"`
+	Restart     StageName `yaml:"restart" json:"restart"`
+	DryRun      *bool     `yaml:"dryRun" json:"dryRun"`
+}
+
+// LoadPlanFile reads the plan file at path and expands it into validated requests.
+func LoadPlanFile(path string) ([]Request, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("plan file: error while reading %s: %w", path, err)
+	}
+	return ParsePlanFile(data)
+}
+
+// ParsePlanFile parses a plan file in YAML or JSON and expands it into validated requests.
+// Unknown fields, like a misspelled dryRun, are rejected and all invalid migrations are reported in the returned error.
+func ParsePlanFile(data []byte) ([]Request, error) {
+	var plan PlanFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&plan); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("plan file: error while parsing: %w", err)
+	}
+	return plan.Requests()
+}
+
+// Requests expanded from the migrations of the plan file, all invalid migrations are reported in the returned error.
+// A plan file without migrations is invalid.
+func (pf *PlanFile) Requests() ([]Request, error) {
+	mapping, err := pf.mapping()
+	if err != nil {
+		return nil, fmt.Errorf("plan file: %w", err)
+	}
+	if len(pf.Migrations) == 0 {
+		return nil, fmt.Errorf("plan file: %w: no migrations", ErrInvalidRequest)
+	}
+	requests := make([]Request, 0, len(pf.Migrations))
+	seen := map[string]int{}
+	var errs []error
+	for index, migration := range pf.Migrations {
+		request, err := pf.request(mapping, migration)
+		if err == nil {
+			err = request.Validate()
+		}
+		if err == nil {
+			if previous, ok := seen[request.FromFQN()]; ok {
+				err = fmt.Errorf("%w: duplicate of migration %d", ErrInvalidRequest, previous)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plan file: migration %d (%s): %w", index, migration.From, err))
+			continue
+		}
+		seen[request.FromFQN()] = index
+		requests = append(requests, request)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return requests, nil
+}
+
+// mapping of the plan file, the Sources and Owners maps are lookup mapping rules applied before Rules.
+func (pf *PlanFile) mapping() (*Mapping, error) {
+	rules := make([]MappingRule, 0, 2+len(pf.Rules))
+	if len(pf.Sources) > 0 {
+		rules = append(rules, MappingRule{Field: MappingSource, Type: MappingLookup, Lookup: pf.Sources})
+	}
+	if len(pf.Owners) > 0 {
+		rules = append(rules, MappingRule{Field: MappingOwner, Type: MappingLookup, Lookup: pf.Owners})
+	}
+	return NewMapping(append(rules, pf.Rules...)...)
+}
+
+// request for the migration, the target is derived using the mapping if it's not set.
+func (pf *PlanFile) request(mapping *Mapping, migration PlanMigration) (Request, error) {
+	request := Request{
+		ID:      migration.ID,
+		Restart: pf.Defaults.Restart,
+		DryRun:  pf.Defaults.DryRun,
+	}
+	var err error
+	if request.FromSource, request.FromOwner, request.FromName, err = _splitFQN(migration.From); err != nil {
+		return request, err
+	}
+	if migration.To != "" {
+		if request.ToSource, request.ToOwner, request.ToName, err = _splitFQN(migration.To); err != nil {
+			return request, err
+		}
+	} else {
+		request = mapping.Apply(request)
+	}
+	callbackURL := pf.Defaults.CallbackURL
+	if migration.CallbackURL != "" {
+		callbackURL = migration.CallbackURL
+	}
+	if callbackURL != "" {
+		request.CallbackURL = &callbackURL
+	}
+	if migration.Restart != "" {
+		request.Restart = migration.Restart
+	}
+	if migration.DryRun != nil {
+		request.DryRun = *migration.DryRun
+	}
+	return request, nil
+}
+
+// _splitFQN splits a fully qualified pipeline name into source, owner and name, the owner can contain slashes.
+func _splitFQN(fqn string) (source, owner, name string, err error) {
+	first := strings.Index(fqn, "/")
+	last := strings.LastIndex(fqn, "/")
+	if first <= 0 || last <= first+1 || last == len(fqn)-1 {
+		return "", "", "", fmt.Errorf("%w: %q is not a fully qualified name like source/owner/name", ErrInvalidRequest, fqn)
+	}
+	return fqn[:first], fqn[first+1 : last], fqn[last+1:], nil
+}
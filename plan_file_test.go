@@ -0,0 +1,151 @@
+package migration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlanFile(t *testing.T) {
+	shouldBe := assert.New(t)
+	requests, err := ParsePlanFile([]byte(`
+defaults:
+  callbackURL: https://callback.example.com
+  restart: last_stage
+sources:
+  bitbucket.org: github.com
+owners:
+  owner1: new-owner1
+migrations:
+  - from: bitbucket.org/owner1/repo1
+  - id: existing-id
+    from: bitbucket.org/owner2/repo2
+    to: github.com/owner2/new-repo2
+    callbackURL: https://other.example.com
+    restart: builds
+    dryRun: true
+`))
+	callbackURL := "https://callback.example.com"
+	otherCallbackURL := "https://other.example.com"
+	if shouldBe.Nil(err) {
+		shouldBe.Equal([]Request{
+			{FromSource: "bitbucket.org", FromOwner: "owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "new-owner1", ToName: "repo1", CallbackURL: &callbackURL, Restart: LastStage},
+			{ID: "existing-id", FromSource: "bitbucket.org", FromOwner: "owner2", FromName: "repo2", ToSource: "github.com", ToOwner: "owner2", ToName: "new-repo2", CallbackURL: &otherCallbackURL, Restart: BuildsStage, DryRun: true},
+		}, requests)
+	}
+}
+
+func TestParsePlanFile_Rules(t *testing.T) {
+	shouldBe := assert.New(t)
+	requests, err := ParsePlanFile([]byte(`
+sources:
+  bitbucket.org: github.com
+rules:
+  - field: owner
+    type: lowercase
+  - field: name
+    type: prefix
+    to: bb-
+migrations:
+  - from: bitbucket.org/Owner1/repo1
+`))
+	if shouldBe.Nil(err) {
+		shouldBe.Equal([]Request{
+			{FromSource: "bitbucket.org", FromOwner: "Owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "bb-repo1"},
+		}, requests)
+	}
+	_, err = ParsePlanFile([]byte("rules:\n  - field: name\n    type: upper\n"))
+	shouldBe.True(errors.Is(err, ErrInvalidMapping))
+}
+
+func TestParsePlanFile_Lookup(t *testing.T) {
+	shouldBe := assert.New(t)
+	requests, err := ParsePlanFile([]byte(`
+sources:
+  bitbucket.org: github.com
+  github.com: gitlab.com
+owners:
+  a: b
+  b: a
+rules:
+  - field: owner
+    type: prefix
+    to: team-
+migrations:
+  - from: bitbucket.org/a/repo1
+  - from: github.com/b/repo2
+`))
+	if shouldBe.Nil(err) {
+		shouldBe.Equal([]Request{
+			{FromSource: "bitbucket.org", FromOwner: "a", FromName: "repo1", ToSource: "github.com", ToOwner: "team-b", ToName: "repo1"},
+			{FromSource: "github.com", FromOwner: "b", FromName: "repo2", ToSource: "gitlab.com", ToOwner: "team-a", ToName: "repo2"},
+		}, requests, "sources don't chain and owners swap as they are a single lookup")
+	}
+}
+
+func TestParsePlanFile_JSON(t *testing.T) {
+	shouldBe := assert.New(t)
+	requests, err := ParsePlanFile([]byte(`{"sources":{"bitbucket.org":"github.com"},"migrations":[{"from":"bitbucket.org/owner1/repo1"}]}`))
+	if shouldBe.Nil(err) {
+		shouldBe.Equal([]Request{
+			{FromSource: "bitbucket.org", FromOwner: "owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "repo1"},
+		}, requests)
+	}
+}
+
+func TestParsePlanFile_Invalid(t *testing.T) {
+	shouldBe := assert.New(t)
+	requests, err := ParsePlanFile([]byte(`
+sources:
+  bitbucket.org: github.com
+migrations:
+  - from: bitbucket.org/owner1
+  - from: github.com/owner1/repo1
+  - from: bitbucket.org/owner1/repo1
+    restart: unknown
+    callbackURL: not-a-url
+  - from: bitbucket.org/owner2/repo2
+  - from: bitbucket.org/owner2/repo2
+`))
+	shouldBe.Nil(requests)
+	shouldBe.True(errors.Is(err, ErrInvalidRequest))
+	shouldBe.ErrorContains(err, `plan file: migration 0 (bitbucket.org/owner1): invalid migration request: "bitbucket.org/owner1" is not a fully qualified name like source/owner/name`)
+	shouldBe.ErrorContains(err, `plan file: migration 1 (github.com/owner1/repo1): invalid migration request: from and to are the same repository github.com/owner1/repo1`)
+	shouldBe.ErrorContains(err, `restart stage "unknown" is unknown`)
+	shouldBe.ErrorContains(err, `callbackURL "not-a-url" is not an absolute http(s) URL`)
+	shouldBe.ErrorContains(err, `plan file: migration 4 (bitbucket.org/owner2/repo2): invalid migration request: duplicate of migration 3`)
+}
+
+func TestParsePlanFile_UnknownField(t *testing.T) {
+	shouldBe := assert.New(t)
+	requests, err := ParsePlanFile([]byte(`
+migrations:
+  - from: bitbucket.org/owner1/repo1
+    dryrun: true
+`))
+	shouldBe.Nil(requests)
+	shouldBe.ErrorContains(err, "field dryrun not found")
+}
+
+func TestParsePlanFile_Empty(t *testing.T) {
+	shouldBe := assert.New(t)
+	for _, data := range []string{"", "sources:\n  bitbucket.org: github.com\n", "migrations: []\n"} {
+		requests, err := ParsePlanFile([]byte(data))
+		shouldBe.Nil(requests)
+		shouldBe.True(errors.Is(err, ErrInvalidRequest), "plan file %q has no migrations", data)
+	}
+}
+
+func TestLoadPlanFile(t *testing.T) {
+	shouldBe := assert.New(t)
+	path := filepath.Join(t.TempDir(), "plan.yaml")
+	shouldBe.Nil(os.WriteFile(path, []byte("migrations:\n  - from: bitbucket.org/owner1/repo1\n    to: github.com/owner1/repo1\n"), 0o600))
+	requests, err := LoadPlanFile(path)
+	shouldBe.Nil(err)
+	shouldBe.Len(requests, 1)
+	_, err = LoadPlanFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	shouldBe.NotNil(err)
+}
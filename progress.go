@@ -0,0 +1,215 @@
+package migration
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultProgressInterval is the minimum time between two progress updates persisted using the Updater, it is also
+// the interval of the heartbeat of a running stage.
+const defaultProgressInterval = 30 * time.Second
+
+// Progress of the stage being executed.
+type Progress struct {
+	Stage     StageName `json:"stage"`
+	Processed int64     `json:"processed"`
+	Total     int64     `json:"total,omitempty"`
+	Current   string    `json:"current,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ProgressReporter is used by executors to report progress of a stage, the task is persisted using the Updater
+// at most once per interval. While the executor runs a heartbeat persists a snapshot of the task every interval, even if
+// no progress is reported, so Task.UpdatedAt of a long-running stage moves. The snapshot is taken when the stage starts
+// and whenever progress is reported, the heartbeat never reads the task so executors can change it without locking.
+// It must be called from the goroutine executing the stage, calls on a nil *ProgressReporter are ignored.
+type ProgressReporter struct {
+	stage    StageName
+	task     *Task
+	updater  Updater
+	interval time.Duration
+	now      func() time.Time
+	// mu guards the snapshot persisted by the heartbeat and persistedAt
+	mu          sync.Mutex
+	snapshot    *Task
+	persistedAt time.Time
+}
+
+func newProgressReporter(stage StageName, task *Task, updater Updater, interval time.Duration) *ProgressReporter {
+	return &ProgressReporter{
+		stage:       stage,
+		task:        task,
+		updater:     updater,
+		interval:    interval,
+		persistedAt: time.Now(),
+		now:         time.Now,
+	}
+}
+
+// SetTotal number of items to be processed by the stage.
+func (pr *ProgressReporter) SetTotal(ctx context.Context, total int64) {
+	if pr == nil {
+		return
+	}
+	pr.progress().Total = total
+	pr.report(ctx)
+}
+
+// Add processed items to the progress, current identifies the item being processed.
+func (pr *ProgressReporter) Add(ctx context.Context, processed int64, current string) {
+	if pr == nil {
+		return
+	}
+	progress := pr.progress()
+	progress.Processed += processed
+	progress.Current = current
+	pr.report(ctx)
+}
+
+// Checkpoint the cursor of the stage, like the last processed ID, so a restarted stage resumes after it using Cursor.
+// Like progress it is persisted at most once per interval, so executors must skip items processed after the persisted
+// checkpoint, see MigratedIDs. The checkpoint is removed when the stage succeeds and not recorded for a dry-run.
+func (pr *ProgressReporter) Checkpoint(ctx context.Context, cursor string) {
+	if pr == nil || pr.task.DryRun {
+		return
+	}
+	if pr.task.Checkpoints == nil {
+		pr.task.Checkpoints = map[StageName]string{}
+	}
+	pr.task.Checkpoints[pr.stage] = cursor
+	pr.progress()
+	pr.report(ctx)
+}
+
+// Cursor of the last checkpoint of the stage or an empty string if the stage starts from the beginning.
+func (pr *ProgressReporter) Cursor() string {
+	if pr == nil {
+		return ""
+	}
+	return pr.task.Checkpoints[pr.stage]
+}
+
+// heartbeat persists a snapshot of the task every interval until the returned stop function is called, which waits
+// for the heartbeat to stop and copies the Version, UpdatedAt and Progress.UpdatedAt persisted by it to the task. Use it around the
+// executor of a stage, both functions must be called from the goroutine executing the stage.
+func (pr *ProgressReporter) heartbeat(ctx context.Context) (stop func()) {
+	if pr == nil || pr.interval <= 0 {
+		return func() {}
+	}
+	pr.progress()
+	pr.mu.Lock()
+	pr.snapshot = _snapshot(pr.task)
+	pr.mu.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(pr.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pr.beat(ctx)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		pr.mu.Lock()
+		defer pr.mu.Unlock()
+		pr.sync()
+		if pr.snapshot.Progress.UpdatedAt.After(pr.progress().UpdatedAt) {
+			pr.task.Progress.UpdatedAt = pr.snapshot.Progress.UpdatedAt
+		}
+		pr.snapshot = nil
+	}
+}
+
+// beat persists the snapshot if the interval has passed since the task was last persisted.
+func (pr *ProgressReporter) beat(ctx context.Context) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	now := pr.now()
+	if now.Sub(pr.persistedAt) < pr.interval {
+		return
+	}
+	pr.persistedAt = now
+	pr.snapshot.Progress.UpdatedAt = now
+	if err := pr.updater(ctx, pr.snapshot); err != nil {
+		log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", pr.snapshot.ID).Str("stage", string(pr.stage)).Msg("error persisting stage heartbeat")
+	}
+}
+
+// sync copies the Version and UpdatedAt of the snapshot persisted by the heartbeat to the task, mu must be held.
+func (pr *ProgressReporter) sync() {
+	if pr.snapshot != nil {
+		pr.task.Version = pr.snapshot.Version
+		pr.task.UpdatedAt = pr.snapshot.UpdatedAt
+	}
+}
+
+// progress of the stage, a new Progress is started if the task holds the progress of another stage.
+func (pr *ProgressReporter) progress() *Progress {
+	if pr.task.Progress == nil || pr.task.Progress.Stage != pr.stage {
+		pr.task.Progress = &Progress{Stage: pr.stage}
+	}
+	return pr.task.Progress
+}
+
+// report persists the task if the interval has passed since it was last persisted and refreshes the snapshot of the heartbeat.
+func (pr *ProgressReporter) report(ctx context.Context) {
+	now := pr.now()
+	pr.task.Progress.UpdatedAt = now
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.sync()
+	if now.Sub(pr.persistedAt) >= pr.interval {
+		pr.persistedAt = now
+		if err := pr.updater(ctx, pr.task); err != nil {
+			log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", pr.task.ID).Str("stage", string(pr.stage)).Msg("error persisting stage progress")
+		}
+		if pr.snapshot != nil {
+			pr.snapshot = _snapshot(pr.task)
+		}
+		return
+	}
+	if pr.snapshot != nil {
+		progress := *pr.task.Progress
+		pr.snapshot.Progress = &progress
+		pr.snapshot.Checkpoints = _copyMap(pr.task.Checkpoints)
+	}
+}
+
+// _snapshot copies the task including the fields persisted as JSON, so it can be persisted while the task changes.
+func _snapshot(task *Task) *Task {
+	snapshot := *task
+	if task.Progress != nil {
+		progress := *task.Progress
+		snapshot.Progress = &progress
+	}
+	if task.Plan != nil {
+		plan := *task.Plan
+		snapshot.Plan = &plan
+	}
+	snapshot.History = append([]HistoryEntry(nil), task.History...)
+	snapshot.StageDurations = _copyMap(task.StageDurations)
+	snapshot.Checkpoints = _copyMap(task.Checkpoints)
+	return &snapshot
+}
+
+func _copyMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[K]V, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
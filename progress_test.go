@@ -0,0 +1,172 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProgressReporter(t *testing.T) {
+	shouldBe := assert.New(t)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockedUpdater := &mockUpdater{}
+	mockedUpdater.On("update", mock.Anything, mock.Anything).Return(nil)
+	task := _WaitingTask()
+	reporter := newProgressReporter(BuildLogObjectsStage, task, mockedUpdater.update, time.Minute)
+	reporter.now = func() time.Time { return now }
+	reporter.persistedAt = now
+
+	reporter.SetTotal(context.TODO(), 100)
+	reporter.Add(context.TODO(), 10, "build-10")
+	mockedUpdater.AssertNotCalled(t, "update", mock.Anything, mock.Anything)
+	shouldBe.Equal(&Progress{Stage: BuildLogObjectsStage, Processed: 10, Total: 100, Current: "build-10", UpdatedAt: now}, task.Progress)
+
+	now = now.Add(time.Minute)
+	reporter.Add(context.TODO(), 5, "build-15")
+	mockedUpdater.AssertNumberOfCalls(t, "update", 1)
+	shouldBe.Equal(&Progress{Stage: BuildLogObjectsStage, Processed: 15, Total: 100, Current: "build-15", UpdatedAt: now}, task.Progress)
+
+	now = now.Add(time.Second)
+	reporter.Add(context.TODO(), 5, "build-20")
+	mockedUpdater.AssertNumberOfCalls(t, "update", 1)
+}
+
+func TestProgressReporter_Nil(t *testing.T) {
+	reporter := ProgressFrom(context.TODO())
+	assert.Nil(t, reporter)
+	assert.NotPanics(t, func() {
+		reporter.SetTotal(context.TODO(), 1)
+		reporter.Add(context.TODO(), 1, "build-1")
+		reporter.Checkpoint(context.TODO(), "1")
+		assert.Equal(t, "", reporter.Cursor())
+	})
+}
+
+func TestStages_Progress(t *testing.T) {
+	shouldBe := assert.New(t)
+	mockedUpdater := &mockUpdater{}
+	mockedUpdater.On("update", mock.Anything, mock.Anything).Return(nil)
+	task := _WaitingTask()
+	ss := NewStages(mockedUpdater.update, task).
+		Set(BuildLogObjectsStage, func(ctx context.Context, task *Task) error {
+			reporter := ProgressFrom(ctx)
+			reporter.SetTotal(ctx, 2)
+			reporter.Add(ctx, 1, "build-1")
+			reporter.Add(ctx, 1, "build-2")
+			return nil
+		})
+	ss.(*stages).progressInterval = 0
+	shouldBe.True(ss.ExecuteNext(context.TODO()))
+	mockedUpdater.AssertNumberOfCalls(t, "update", 4)
+	if shouldBe.NotNil(task.Progress) {
+		shouldBe.Equal(BuildLogObjectsStage, task.Progress.Stage)
+		shouldBe.Equal(int64(2), task.Progress.Processed)
+		shouldBe.Equal(int64(2), task.Progress.Total)
+		shouldBe.Equal("build-2", task.Progress.Current)
+	}
+}
+
+func TestStages_Checkpoint(t *testing.T) {
+	shouldBe := assert.New(t)
+	mockedUpdater := &mockUpdater{}
+	mockedUpdater.On("update", mock.Anything, mock.Anything).Return(nil)
+	ledger := NewMemoryLedger()
+	ctx := ContextWithLedger(context.TODO(), ledger)
+	task := _WaitingTask()
+	migrated := make([]int64, 0)
+	failAt := int64(3)
+	executor := func(ctx context.Context, task *Task) error {
+		reporter := ProgressFrom(ctx)
+		skip, err := MigratedIDs(ctx, task, EntityBuild)
+		if err != nil {
+			return err
+		}
+		cursor, _ := strconv.ParseInt(reporter.Cursor(), 10, 64)
+		for id := cursor + 1; id <= 5; id++ {
+			if _, ok := skip[id]; ok {
+				continue
+			}
+			if id == failAt {
+				return fmt.Errorf("failed at build %d", id)
+			}
+			migrated = append(migrated, id)
+			if err = RecordChanges(ctx, task, EntityBuild, Change{FromID: id, ToID: id + 10}); err != nil {
+				return err
+			}
+			reporter.Checkpoint(ctx, strconv.FormatInt(id, 10))
+		}
+		return nil
+	}
+
+	ss := NewStages(mockedUpdater.update, task).Set(BuildsStage, executor)
+	ss.(*stages).progressInterval = time.Hour
+	shouldBe.False(ss.ExecuteNext(ctx))
+	shouldBe.Equal(map[StageName]string{BuildsStage: "2"}, task.Checkpoints, "checkpoint is kept when the stage fails")
+
+	task.Checkpoints[BuildsStage] = "1"
+	failAt = 0
+	ss = NewStages(mockedUpdater.update, task).Set(BuildsStage, executor)
+	shouldBe.True(ss.ExecuteNext(ctx))
+	shouldBe.Equal([]int64{1, 2, 3, 4, 5}, migrated, "builds after the checkpoint already in the ledger are skipped")
+	shouldBe.Empty(task.Checkpoints, "checkpoint is removed when the stage succeeds")
+}
+
+func TestStages_Heartbeat(t *testing.T) {
+	shouldBe := assert.New(t)
+	var updates atomic.Int32
+	updater := func(_ context.Context, _ *Task) error {
+		updates.Add(1)
+		return nil
+	}
+	task := _WaitingTask()
+	ss := NewStages(updater, task).
+		Set(BuildLogObjectsStage, func(ctx context.Context, _ *Task) error {
+			// a single long-running item without any progress reported
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		})
+	ss.(*stages).progressInterval = 10 * time.Millisecond
+	shouldBe.True(ss.ExecuteNext(context.TODO()))
+	shouldBe.GreaterOrEqual(updates.Load(), int32(3), "heartbeat persists the task while the stage runs")
+	if shouldBe.NotNil(task.Progress) {
+		shouldBe.Equal(BuildLogObjectsStage, task.Progress.Stage)
+		shouldBe.False(task.Progress.UpdatedAt.IsZero())
+	}
+	afterStage := updates.Load()
+	time.Sleep(30 * time.Millisecond)
+	shouldBe.Equal(afterStage, updates.Load(), "heartbeat stops with the stage")
+}
+
+func TestStages_Heartbeat_ExecutorChangesTask(t *testing.T) {
+	shouldBe := assert.New(t)
+	store := NewSQLStore(_SQLiteDB(t))
+	task := _WaitingTask()
+	task.ID = ""
+	task.DryRun = true
+	shouldBe.Nil(store.Create(context.TODO(), task))
+	ss := NewStages(store.Update, task).
+		Set(ReleasesStage, func(ctx context.Context, task *Task) error {
+			for i := 0; i < 50; i++ {
+				// changes made without locking while the heartbeat persists a snapshot
+				task.Releases++
+				task.Plan.Changes.Add(Changes{Releases: 1})
+				time.Sleep(time.Millisecond)
+			}
+			return nil
+		})
+	ss.(*stages).progressInterval = 5 * time.Millisecond
+	shouldBe.True(ss.ExecuteNext(context.TODO()))
+	shouldBe.Equal(StatusInProgress, task.Status)
+	stored, err := store.Get(context.TODO(), task.ID)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(task.Version, stored.Version, "versions persisted by the heartbeat are synced to the task")
+		shouldBe.Equal(50, stored.Releases)
+		shouldBe.Equal(50, stored.Plan.Changes.Releases)
+	}
+}
@@ -0,0 +1,48 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+var ErrInvalidRequest = fmt.Errorf("invalid migration request")
+
+// Validate the request, all problems are reported in the returned error which wraps ErrInvalidRequest.
+func (r *Request) Validate() error {
+	var errs []error
+	for _, field := range []struct{ name, value string }{
+		{"fromSource", r.FromSource},
+		{"fromOwner", r.FromOwner},
+		{"fromName", r.FromName},
+		{"toSource", r.ToSource},
+		{"toOwner", r.ToOwner},
+		{"toName", r.ToName},
+	} {
+		if field.value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", field.name))
+		}
+	}
+	for _, repository := range [][3]string{{r.FromSource, r.FromOwner, r.FromName}, {r.ToSource, r.ToOwner, r.ToName}} {
+		if repository[0] != "" {
+			if err := DefaultSources.validate(repository[0], repository[1], repository[2]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) == 0 && r.FromFQN() == r.ToFQN() {
+		errs = append(errs, fmt.Errorf("from and to are the same repository %s", r.FromFQN()))
+	}
+	if r.Restart != "" && !r.Restart.IsValid() {
+		errs = append(errs, fmt.Errorf("restart stage %q is unknown", r.Restart))
+	}
+	if r.CallbackURL != nil && *r.CallbackURL != "" {
+		if u, err := url.Parse(*r.CallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("callbackURL %q is not an absolute http(s) URL", *r.CallbackURL))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrRollbackMismatch = fmt.Errorf("rolled back rows differ from the ledger")
+
+// Rollback the migration task using the client, the target rows recorded in the ledger are removed by their ToID and
+// the number of removed rows of every entity type is checked against the ledger. A task without recorded changes is
+// rolled back as a whole using Client.RollbackMigration. The changes of the task are cleared if they match, otherwise
+// ErrRollbackMismatch is returned together with the rolled back changes and the ledger is kept for inspection.
+func Rollback(ctx context.Context, client Client, ledger Ledger, taskID string) (*Changes, error) {
+	entities := []EntityType{EntityBuild, EntityRelease, EntityBuildLog, EntityReleaseLog}
+	recorded := map[EntityType][]int64{}
+	total := 0
+	for _, entity := range entities {
+		changes, err := ledger.Changes(ctx, taskID, entity)
+		if err != nil {
+			return nil, fmt.Errorf("rollback of task %s: %w", taskID, err)
+		}
+		for _, change := range changes {
+			recorded[entity] = append(recorded[entity], change.ToID)
+		}
+		total += len(changes)
+	}
+	if total == 0 {
+		rolledBack, err := client.RollbackMigration(taskID)
+		if err != nil {
+			return nil, fmt.Errorf("rollback of task %s: %w", taskID, err)
+		}
+		log.Info().Str("module", "github.com/estafette/migration").Str("taskID", taskID).Int("builds", rolledBack.Builds).Int("releases", rolledBack.Releases).Msg("rolled back migration without recorded changes")
+		return rolledBack, nil
+	}
+	rolledBack := &Changes{}
+	mismatches := make([]string, 0)
+	for _, entity := range entities {
+		toIDs := recorded[entity]
+		if len(toIDs) == 0 {
+			continue
+		}
+		removed, err := client.RollbackMigrationChanges(taskID, entity, toIDs)
+		if err != nil {
+			return rolledBack, fmt.Errorf("rollback of task %s: %w", taskID, err)
+		}
+		rolledBack.Add(*removed)
+		counts := map[EntityType]int{EntityBuild: removed.Builds, EntityRelease: removed.Releases, EntityBuildLog: removed.BuildLogs, EntityReleaseLog: removed.ReleaseLogs}
+		if counts[entity] != len(toIDs) {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %d recorded, %d rolled back", entity, len(toIDs), counts[entity]))
+		}
+	}
+	if len(mismatches) > 0 {
+		return rolledBack, fmt.Errorf("%w: task %s %s", ErrRollbackMismatch, taskID, strings.Join(mismatches, ", "))
+	}
+	if err := ledger.Clear(ctx, taskID); err != nil {
+		return rolledBack, fmt.Errorf("rollback of task %s: %w", taskID, err)
+	}
+	log.Info().Str("module", "github.com/estafette/migration").Str("taskID", taskID).Int("builds", rolledBack.Builds).Int("releases", rolledBack.Releases).Msg("rolled back migration")
+	return rolledBack, nil
+}
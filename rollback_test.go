@@ -0,0 +1,70 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rollbackClient rolls back the given changes of a task without recorded changes and removes the rows with the given
+// IDs which exist in the target pipeline.
+type rollbackClient struct {
+	Client
+	changes *Changes
+	rows    map[EntityType]map[int64]bool
+}
+
+func (c *rollbackClient) RollbackMigration(string) (*Changes, error) {
+	return c.changes, nil
+}
+
+func (c *rollbackClient) RollbackMigrationChanges(_ string, entity EntityType, toIDs []int64) (*Changes, error) {
+	removed := 0
+	for _, id := range toIDs {
+		if c.rows[entity][id] {
+			delete(c.rows[entity], id)
+			removed++
+		}
+	}
+	switch entity {
+	case EntityBuild:
+		return &Changes{Builds: removed, BuildLogs: removed}, nil
+	case EntityRelease:
+		return &Changes{Releases: removed}, nil
+	default:
+		return &Changes{}, nil
+	}
+}
+
+func TestRollback(t *testing.T) {
+	shouldBe := assert.New(t)
+	ctx := context.TODO()
+	ledger := NewMemoryLedger()
+	shouldBe.Nil(ledger.Record(ctx, "task-1", EntityBuild, Change{FromID: 1, ToID: 11}, Change{FromID: 2, ToID: 12}))
+	shouldBe.Nil(ledger.Record(ctx, "task-1", EntityRelease, Change{FromID: 5, ToID: 15}))
+	client := &rollbackClient{rows: map[EntityType]map[int64]bool{
+		EntityBuild:   {11: true, 13: true},
+		EntityRelease: {15: true},
+	}}
+
+	changes, err := Rollback(ctx, client, ledger, "task-1")
+	shouldBe.True(errors.Is(err, ErrRollbackMismatch))
+	shouldBe.ErrorContains(err, "build: 2 recorded, 1 rolled back")
+	shouldBe.Equal(&Changes{Builds: 1, BuildLogs: 1, Releases: 1}, changes)
+	shouldBe.Equal(map[int64]bool{13: true}, client.rows[EntityBuild], "rows which aren't recorded are kept")
+	recorded, _ := ledger.Changes(ctx, "task-1", EntityBuild)
+	shouldBe.Len(recorded, 2, "ledger is kept on mismatch")
+
+	client.rows = map[EntityType]map[int64]bool{EntityBuild: {11: true, 12: true}, EntityRelease: {15: true}}
+	changes, err = Rollback(ctx, client, ledger, "task-1")
+	shouldBe.Nil(err)
+	shouldBe.Equal(&Changes{Builds: 2, BuildLogs: 2, Releases: 1}, changes)
+	recorded, _ = ledger.Changes(ctx, "task-1", EntityBuild)
+	shouldBe.Empty(recorded)
+
+	changes, err = Rollback(ctx, &rollbackClient{changes: &Changes{Builds: 3}}, ledger, "task-1")
+	shouldBe.Nil(err)
+	shouldBe.Equal(&Changes{Builds: 3}, changes, "a task without recorded changes is rolled back as a whole")
+}
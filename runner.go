@@ -0,0 +1,369 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultConcurrency  = 1
+	defaultPollInterval = 10 * time.Second
+	defaultLeaseTTL     = time.Minute
+)
+
+// TaskSource provides queued tasks to a Runner and persists their progress, Store implements it.
+type TaskSource interface {
+	// ClaimNext queued task, returns ErrNoQueuedTasks if there is none.
+	ClaimNext(ctx context.Context) (*Task, error)
+	// Get task using task ID, used to queue tasks of expired leases again.
+	Get(ctx context.Context, taskID string) (*Task, error)
+	// List tasks with the given status, used to queue in progress tasks without lease again.
+	List(ctx context.Context, status Status) ([]*Task, error)
+	// Update the task, used as Updater of the task Stages.
+	Update(ctx context.Context, task *Task) error
+}
+
+// RunnerConfig of a Runner, zero values are replaced by defaults.
+type RunnerConfig struct {
+	// Concurrency is the maximum number of tasks executed at the same time, defaults to 1.
+	Concurrency int
+	// PollInterval is the wait time between polls when there are no queued tasks, defaults to 10s.
+	PollInterval time.Duration
+	// Leaser is optional, if set a lease is held for every executed task and renewed until the task stops.
+	// Tasks of expired leases are queued again.
+	Leaser Leaser
+	// WorkerID is the owner of the leases held by the Runner, defaults to a random ID.
+	WorkerID string
+	// LeaseTTL is the time after which a lease expires if it's not renewed, defaults to 1m.
+	// In progress tasks without lease which weren't updated for LeaseTTL are queued again.
+	LeaseTTL time.Duration
+	// ReclaimInterval is the wait time between reclaims of tasks of expired leases, defaults to LeaseTTL.
+	ReclaimInterval time.Duration
+	// Ledger is optional, if set it is carried by the context of executors to record changes, see RecordChanges.
+	Ledger Ledger
+}
+
+// Runner claims queued tasks from a TaskSource and executes their stages using the registered executors.
+type Runner struct {
+	source    TaskSource
+	executors map[StageName]Executor
+	config    RunnerConfig
+	// running task IDs, they are never reclaimed by this runner as it holds or is acquiring their lease
+	running sync.Map
+}
+
+// NewRunner creates a Runner which builds Stages for every claimed task from the given executors.
+func NewRunner(source TaskSource, executors map[StageName]Executor, config RunnerConfig) *Runner {
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.WorkerID == "" {
+		config.WorkerID = uuid.NewString()
+	}
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = defaultLeaseTTL
+	}
+	if config.ReclaimInterval <= 0 {
+		config.ReclaimInterval = config.LeaseTTL
+	}
+	return &Runner{
+		source:    source,
+		executors: executors,
+		config:    config,
+	}
+}
+
+// Run claims and executes tasks until ctx is canceled, then waits for running tasks to stop.
+// Tasks interrupted by the cancellation are queued again, so they restart from their LastStep.
+// If ctx carries Metrics the number of tasks by status is refreshed every PollInterval, see ContextWithMetrics.
+func (r *Runner) Run(ctx context.Context) error {
+	slots := make(chan struct{}, r.config.Concurrency)
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	if MetricsFrom(ctx) != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.observeEvery(ctx)
+		}()
+	}
+	if r.config.Leaser != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.reclaimEvery(ctx)
+		}()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case slots <- struct{}{}:
+		}
+		task, err := r.source.ClaimNext(ctx)
+		if err != nil {
+			<-slots
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !errors.Is(err, ErrNoQueuedTasks) {
+				log.Error().Str("module", "github.com/estafette/migration").Err(err).Msg("error claiming migration task")
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(r.config.PollInterval):
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			r.lease(ctx, task)
+		}()
+	}
+}
+
+// lease the task while it's executed, the lease is renewed every third of LeaseTTL and the task is stopped if it is lost.
+func (r *Runner) lease(ctx context.Context, task *Task) {
+	if r.config.Leaser == nil {
+		r.execute(ctx, task)
+		return
+	}
+	r.running.Store(task.ID, true)
+	defer r.running.Delete(task.ID)
+	lease, err := r.config.Leaser.Acquire(ctx, task.ID, r.config.WorkerID, r.config.LeaseTTL)
+	if err != nil {
+		log.Warn().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("error acquiring task lease")
+		r.requeue(task, "task lease not acquired")
+		return
+	}
+	defer func() {
+		if err := r.config.Leaser.Release(context.Background(), lease); err != nil {
+			log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("error releasing task lease")
+		}
+	}()
+	taskCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go r.heartbeat(taskCtx, cancel, lease)
+	r.execute(taskCtx, task)
+}
+
+// heartbeat renews the lease until ctx is done and cancels the task if the lease can't be renewed.
+func (r *Runner) heartbeat(ctx context.Context, cancel context.CancelFunc, lease *Lease) {
+	ticker := time.NewTicker(r.config.LeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.config.Leaser.Renew(ctx, lease, r.config.LeaseTTL); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", lease.TaskID).Msg("error renewing task lease, stopping task")
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// reclaimEvery ReclaimInterval until ctx is done, starting immediately.
+func (r *Runner) reclaimEvery(ctx context.Context) {
+	ticker := time.NewTicker(r.config.ReclaimInterval)
+	defer ticker.Stop()
+	for {
+		r.reclaim(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// observeEvery refreshes the number of tasks by status every PollInterval until ctx is done.
+func (r *Runner) observeEvery(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := MetricsFrom(ctx).observeTasks(ctx, r.source); err != nil && ctx.Err() == nil {
+			log.Error().Str("module", "github.com/estafette/migration").Err(err).Msg("error observing migration tasks")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reclaim tasks of expired leases and stale tasks without lease by queueing them again, the task version guards
+// against concurrent reclaims.
+func (r *Runner) reclaim(ctx context.Context) {
+	r.reclaimExpired(ctx)
+	r.reclaimStale(ctx)
+}
+
+// reclaimExpired queues the tasks of expired leases again, tasks executed by this runner are skipped.
+func (r *Runner) reclaimExpired(ctx context.Context) {
+	leases, err := r.config.Leaser.Expired(ctx)
+	if err != nil {
+		log.Error().Str("module", "github.com/estafette/migration").Err(err).Msg("error listing expired task leases")
+		return
+	}
+	for _, lease := range leases {
+		// a lease of a task this runner executes expired as renewing it was late, it's renewed or the task stops itself
+		if _, running := r.running.Load(lease.TaskID); running {
+			continue
+		}
+		var task *Task
+		if task, err = r.source.Get(ctx, lease.TaskID); err != nil && !errors.Is(err, ErrTaskNotFound) {
+			log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", lease.TaskID).Msg("error reclaiming task of expired lease")
+			continue
+		}
+		if task != nil && task.Status == StatusInProgress {
+			if err = task.Transition(StatusQueued, task.LastStep); err == nil {
+				err = r.source.Update(ctx, task)
+			}
+			if err != nil {
+				log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", lease.TaskID).Msg("error reclaiming task of expired lease")
+				continue
+			}
+			log.Warn().Str("module", "github.com/estafette/migration").Str("taskID", lease.TaskID).Str("owner", lease.Owner).Msg("task lease expired, requeued")
+		}
+		if err = r.config.Leaser.Release(ctx, lease); err != nil {
+			log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", lease.TaskID).Msg("error releasing expired task lease")
+		}
+	}
+}
+
+// reclaimStale queues in progress tasks again which weren't updated for LeaseTTL and have no lease, like a task of a
+// worker which crashed after claiming it and before acquiring its lease. The lease is acquired while requeueing, so
+// a task leased by a running worker is never reclaimed.
+func (r *Runner) reclaimStale(ctx context.Context) {
+	tasks, err := r.source.List(ctx, StatusInProgress)
+	if err != nil {
+		log.Error().Str("module", "github.com/estafette/migration").Err(err).Msg("error listing in progress tasks")
+		return
+	}
+	staleBefore := time.Now().Add(-r.config.LeaseTTL)
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, running := r.running.Load(task.ID); running || task.UpdatedAt.After(staleBefore) {
+			continue
+		}
+		lease, err := r.config.Leaser.Acquire(ctx, task.ID, r.config.WorkerID, r.config.LeaseTTL)
+		if errors.Is(err, ErrLeaseHeld) {
+			continue
+		}
+		if err != nil {
+			log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("error reclaiming stale task")
+			continue
+		}
+		if err = task.Transition(StatusQueued, task.LastStep); err == nil {
+			err = r.source.Update(ctx, task)
+		}
+		if err != nil {
+			log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("error reclaiming stale task")
+		} else {
+			log.Warn().Str("module", "github.com/estafette/migration").Str("taskID", task.ID).Time("updatedAt", task.UpdatedAt).Msg("task without lease is stale, requeued")
+		}
+		if err = r.config.Leaser.Release(ctx, lease); err != nil {
+			log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("error releasing stale task lease")
+		}
+	}
+}
+
+// execute all stages of the task until one fails or ctx is canceled.
+func (r *Runner) execute(ctx context.Context, task *Task) {
+	log.Info().Str("module", "github.com/estafette/migration").Str("taskID", task.ID).Msg("task claimed")
+	ctx = ContextWithWorkerID(ctx, r.config.WorkerID)
+	if r.config.Ledger != nil {
+		ctx = ContextWithLedger(ctx, r.config.Ledger)
+	}
+	ss, err := NewStagesWithRestart(r.source.Update, task)
+	if err != nil {
+		r.fail(task, err)
+		return
+	}
+	// executors are set in stage order, so the stages don't depend on the iteration order of the map
+	for _, name := range stageNames {
+		if executor, ok := r.executors[name]; ok {
+			ss.Set(name, executor)
+		}
+	}
+	stopped := false
+	for ss.HasNext() && ctx.Err() == nil {
+		if !ss.ExecuteNext(ctx) {
+			stopped = true
+			break
+		}
+	}
+	// a task still in progress with a stopped stage or stages left was interrupted, failed tasks stay failed even if
+	// they failed while ctx was canceled
+	if ctx.Err() != nil && task.Status == StatusInProgress && (stopped || ss.HasNext()) {
+		r.requeue(task, "task interrupted")
+		return
+	}
+	// a task still in progress without stages left, like a claimed task without remaining stages, is completed
+	if !stopped && task.Status == StatusInProgress {
+		r.complete(task)
+	}
+}
+
+// complete the task which has no stages left to execute.
+func (r *Runner) complete(task *Task) {
+	if err := task.Transition(StatusCompleted, task.LastStep); err != nil {
+		log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("error completing task")
+		return
+	}
+	if err := r.source.Update(context.Background(), task); err != nil {
+		log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("error completing task")
+		return
+	}
+	log.Info().Str("module", "github.com/estafette/migration").Str("taskID", task.ID).Str("lastStep", task.LastStep.String()).Msg("task has no stages left, completed")
+}
+
+// fail the task which can't be executed.
+func (r *Runner) fail(task *Task, err error) {
+	log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("task can't be executed")
+	errorDetails := err.Error()
+	task.ErrorDetails = &errorDetails
+	if err = task.Transition(StatusFailed, task.LastStep); err != nil {
+		log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("error failing task")
+		return
+	}
+	if err = r.source.Update(context.Background(), task); err != nil {
+		log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Msg("error failing task")
+	}
+}
+
+// requeue a claimed task which isn't executed any further for the given reason, it uses a new context as the runner
+// context may already be canceled.
+func (r *Runner) requeue(task *Task, reason string) {
+	if err := task.Transition(StatusQueued, task.LastStep); err != nil {
+		log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Str("reason", reason).Msg("error requeueing task")
+		return
+	}
+	if err := r.source.Update(context.Background(), task); err != nil {
+		log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Str("reason", reason).Msg("error requeueing task")
+		return
+	}
+	log.Warn().Str("module", "github.com/estafette/migration").Str("taskID", task.ID).Str("lastStep", task.LastStep.String()).Msg(reason + ", requeued")
+}
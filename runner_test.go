@@ -0,0 +1,233 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Run(t *testing.T) {
+	shouldBe := assert.New(t)
+	store := NewSQLStore(_SQLiteDB(t))
+	for i := 0; i < 4; i++ {
+		task := _WaitingTask()
+		task.ID = ""
+		shouldBe.Nil(store.Create(context.TODO(), task))
+	}
+	var executed atomic.Int32
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	countingExecutor := func(_ context.Context, _ *Task) error {
+		executed.Add(1)
+		return nil
+	}
+	runner := NewRunner(store, map[StageName]Executor{
+		ReleasesStage:  countingExecutor,
+		BuildsStage:    countingExecutor,
+		CompletedStage: CompletedExecutor,
+	}, RunnerConfig{Concurrency: 2, PollInterval: 10 * time.Millisecond})
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+	shouldBe.Eventually(func() bool {
+		completed, err := store.List(context.TODO(), StatusCompleted)
+		return err == nil && len(completed) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	shouldBe.Nil(<-done)
+	shouldBe.Equal(int32(8), executed.Load())
+}
+
+func TestRunner_Run_NoStagesLeft(t *testing.T) {
+	shouldBe := assert.New(t)
+	store := NewSQLStore(_SQLiteDB(t))
+	task := _WaitingTask()
+	task.ID = ""
+	task.LastStep = StepBuildsDone
+	shouldBe.Nil(store.Create(context.TODO(), task))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	runner := NewRunner(store, map[StageName]Executor{
+		ReleasesStage: func(_ context.Context, _ *Task) error { return nil },
+		BuildsStage:   func(_ context.Context, _ *Task) error { return nil },
+	}, RunnerConfig{PollInterval: 10 * time.Millisecond})
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+	shouldBe.Eventually(func() bool {
+		completed, err := store.Get(context.TODO(), task.ID)
+		return err == nil && completed.Status == StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond, "a claimed task without stages left doesn't stay in progress")
+	cancel()
+	shouldBe.Nil(<-done)
+}
+
+func TestRunner_Run_Interrupted(t *testing.T) {
+	shouldBe := assert.New(t)
+	store := NewSQLStore(_SQLiteDB(t))
+	task := _WaitingTask()
+	shouldBe.Nil(store.Create(context.TODO(), task))
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	runner := NewRunner(store, map[StageName]Executor{
+		ReleasesStage: func(_ context.Context, _ *Task) error { return nil },
+		BuildsStage: func(ctx context.Context, _ *Task) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		CompletedStage: CompletedExecutor,
+	}, RunnerConfig{PollInterval: 10 * time.Millisecond})
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+	<-started
+	cancel()
+	shouldBe.Nil(<-done)
+	interrupted, err := store.Get(context.TODO(), task.ID)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(StatusQueued, interrupted.Status)
+		shouldBe.Equal(StepBuildsFailed, interrupted.LastStep)
+	}
+}
+
+func TestRunner_Run_FailedWhileInterrupted(t *testing.T) {
+	shouldBe := assert.New(t)
+	store := NewSQLStore(_SQLiteDB(t))
+	task := _WaitingTask()
+	shouldBe.Nil(store.Create(context.TODO(), task))
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	runner := NewRunner(store, map[StageName]Executor{
+		BuildsStage: func(ctx context.Context, _ *Task) error {
+			close(started)
+			<-ctx.Done()
+			return errors.New("builds failed")
+		},
+		CompletedStage: CompletedExecutor,
+	}, RunnerConfig{PollInterval: 10 * time.Millisecond})
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+	<-started
+	cancel()
+	shouldBe.Nil(<-done)
+	failed, err := store.Get(context.TODO(), task.ID)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(StatusFailed, failed.Status)
+		shouldBe.Equal(StepBuildsFailed, failed.LastStep)
+		shouldBe.Equal("builds failed", *failed.ErrorDetails)
+	}
+}
+
+func TestRunner_Run_ReclaimsExpiredLease(t *testing.T) {
+	shouldBe := assert.New(t)
+	store := NewSQLStore(_SQLiteDB(t))
+	task := _WaitingTask()
+	task.Status = StatusInProgress
+	shouldBe.Nil(store.Create(context.TODO(), task))
+	leaser := NewMemoryLeaser()
+	_, err := leaser.Acquire(context.TODO(), task.ID, "crashed-worker", -time.Second)
+	shouldBe.Nil(err)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	runner := NewRunner(store, map[StageName]Executor{
+		BuildsStage:    func(_ context.Context, _ *Task) error { return nil },
+		CompletedStage: CompletedExecutor,
+	}, RunnerConfig{PollInterval: 10 * time.Millisecond, Leaser: leaser, WorkerID: "worker-1"})
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+	shouldBe.Eventually(func() bool {
+		completed, err := store.Get(context.TODO(), task.ID)
+		return err == nil && completed.Status == StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	shouldBe.Nil(<-done)
+	expired, err := leaser.Expired(context.TODO())
+	shouldBe.Nil(err)
+	shouldBe.Empty(expired)
+}
+
+func TestRunner_ReclaimExpired_SkipsRunningTask(t *testing.T) {
+	shouldBe := assert.New(t)
+	store := NewSQLStore(_SQLiteDB(t))
+	task := _WaitingTask()
+	task.Status = StatusInProgress
+	shouldBe.Nil(store.Create(context.TODO(), task))
+	leaser := NewMemoryLeaser()
+	_, err := leaser.Acquire(context.TODO(), task.ID, "worker-1", -time.Second)
+	shouldBe.Nil(err)
+	runner := NewRunner(store, map[StageName]Executor{}, RunnerConfig{Leaser: leaser, WorkerID: "worker-1"})
+	runner.running.Store(task.ID, true)
+	runner.reclaimExpired(context.TODO())
+	running, err := store.Get(context.TODO(), task.ID)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(StatusInProgress, running.Status, "a task executed by the runner isn't reclaimed")
+	}
+	expired, err := leaser.Expired(context.TODO())
+	shouldBe.Nil(err)
+	shouldBe.Len(expired, 1)
+}
+
+func TestRunner_Run_ReclaimsStaleTaskWithoutLease(t *testing.T) {
+	shouldBe := assert.New(t)
+	db := _SQLiteDB(t)
+	store := NewSQLStore(db)
+	task := _WaitingTask()
+	task.Status = StatusInProgress
+	shouldBe.Nil(store.Create(context.TODO(), task))
+	_, err := db.Exec(`UPDATE migration_tasks SET updated_at = $1 WHERE id = $2`, time.Now().UTC().Add(-time.Hour), task.ID)
+	shouldBe.Nil(err)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	runner := NewRunner(store, map[StageName]Executor{
+		BuildsStage:    func(_ context.Context, _ *Task) error { return nil },
+		CompletedStage: CompletedExecutor,
+	}, RunnerConfig{PollInterval: 10 * time.Millisecond, Leaser: NewMemoryLeaser(), WorkerID: "worker-1"})
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+	shouldBe.Eventually(func() bool {
+		completed, err := store.Get(context.TODO(), task.ID)
+		return err == nil && completed.Status == StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	shouldBe.Nil(<-done)
+}
+
+// failingLeaser fails to acquire the first lease.
+type failingLeaser struct {
+	Leaser
+	acquired atomic.Int32
+}
+
+func (fl *failingLeaser) Acquire(ctx context.Context, taskID, owner string, ttl time.Duration) (*Lease, error) {
+	if fl.acquired.Add(1) == 1 {
+		return nil, errors.New("database unavailable")
+	}
+	return fl.Leaser.Acquire(ctx, taskID, owner, ttl)
+}
+
+func TestRunner_Run_RequeuesWithoutLease(t *testing.T) {
+	shouldBe := assert.New(t)
+	store := NewSQLStore(_SQLiteDB(t))
+	task := _WaitingTask()
+	shouldBe.Nil(store.Create(context.TODO(), task))
+	leaser := &failingLeaser{Leaser: NewMemoryLeaser()}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	runner := NewRunner(store, map[StageName]Executor{
+		BuildsStage:    func(_ context.Context, _ *Task) error { return nil },
+		CompletedStage: CompletedExecutor,
+	}, RunnerConfig{PollInterval: 10 * time.Millisecond, Leaser: leaser, WorkerID: "worker-1"})
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+	shouldBe.Eventually(func() bool {
+		completed, err := store.Get(context.TODO(), task.ID)
+		return err == nil && completed.Status == StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	shouldBe.Nil(<-done)
+	shouldBe.GreaterOrEqual(leaser.acquired.Load(), int32(2))
+}
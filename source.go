@@ -0,0 +1,160 @@
+package migration
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownSource = fmt.Errorf("unknown source")
+	ErrInvalidSource = fmt.Errorf("invalid source")
+	hostname         = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*(:[0-9]+)?$`)
+)
+
+const (
+	// SourceGitHub owners are a single user or organization.
+	SourceGitHub SourceKind = "github"
+	// SourceBitbucket owners are a single workspace.
+	SourceBitbucket SourceKind = "bitbucket"
+	// SourceGitLab owners are a group followed by optional nested subgroups, like group/subgroup.
+	SourceGitLab SourceKind = "gitlab"
+	// SourceAzureDevOps owners are an organization and project, like organization/project.
+	SourceAzureDevOps SourceKind = "azure-devops"
+)
+
+// DefaultSources is the SourceRegistry used by Request and Task, register self-hosted enterprise hosts on it.
+var DefaultSources = NewSourceRegistry(
+	Source{Host: "github.com", Name: "github", Kind: SourceGitHub},
+	Source{Host: "bitbucket.org", Name: "bitbucket", Kind: SourceBitbucket, Aliases: []string{"bitbucket.com"}},
+	Source{Host: "gitlab.com", Name: "gitlab", Kind: SourceGitLab},
+	Source{Host: "dev.azure.com", Name: "azure", Kind: SourceAzureDevOps},
+)
+
+// SourceKind is the type of SCM of a source, it defines the structure of owners.
+type SourceKind string
+
+// Source of repositories like github.com.
+type Source struct {
+	// Host is the canonical host of the source, used in fully qualified names.
+	Host string
+	// Name is the short name of the source, like github for github.com.
+	Name string
+	// Kind of SCM, sources without kind are validated like SourceGitHub.
+	Kind SourceKind
+	// Aliases are other hosts of the source, they are replaced by Host.
+	Aliases []string
+	// Enterprise is true for self-hosted instances, like github.example.io.
+	Enterprise bool
+}
+
+// SourceRegistry knows the canonical hosts, short names and aliases of sources.
+type SourceRegistry struct {
+	mu      sync.RWMutex
+	sources map[string]Source
+}
+
+// NewSourceRegistry returns a registry with the given sources, it panics if they are invalid.
+func NewSourceRegistry(sources ...Source) *SourceRegistry {
+	sr := &SourceRegistry{sources: map[string]Source{}}
+	for _, source := range sources {
+		if err := sr.Register(source); err != nil {
+			panic(err)
+		}
+	}
+	return sr
+}
+
+// Register the source, it returns an error if the host or an alias is invalid or already registered to another source.
+func (sr *SourceRegistry) Register(source Source) error {
+	source.Host = strings.ToLower(source.Host)
+	if source.Name == "" {
+		return fmt.Errorf("%w: name of %q is required", ErrInvalidSource, source.Host)
+	}
+	hosts := append([]string{source.Host}, source.Aliases...)
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	for index, host := range hosts {
+		host = strings.ToLower(host)
+		hosts[index] = host
+		if !hostname.MatchString(host) {
+			return fmt.Errorf("%w: %q is not a valid host", ErrInvalidSource, host)
+		}
+		if existing, ok := sr.sources[host]; ok && existing.Host != source.Host {
+			return fmt.Errorf("%w: %q is already registered for %s", ErrInvalidSource, host, existing.Host)
+		}
+	}
+	source.Aliases = hosts[1:]
+	for _, host := range hosts {
+		sr.sources[host] = source
+	}
+	return nil
+}
+
+// Lookup the source using its host or one of its aliases, case-insensitive.
+func (sr *SourceRegistry) Lookup(host string) (Source, bool) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	source, ok := sr.sources[strings.ToLower(host)]
+	return source, ok
+}
+
+// Canonical host of the source, unknown hosts are returned unchanged.
+func (sr *SourceRegistry) Canonical(host string) string {
+	if source, ok := sr.Lookup(host); ok {
+		return source.Host
+	}
+	return host
+}
+
+// Name of the source, for unknown hosts a trailing .com or .org is removed.
+func (sr *SourceRegistry) Name(host string) string {
+	if source, ok := sr.Lookup(host); ok {
+		return source.Name
+	}
+	for _, tld := range []string{".com", ".org"} {
+		if strings.HasSuffix(host, tld) {
+			return strings.TrimSuffix(host, tld)
+		}
+	}
+	return host
+}
+
+// validate returns ErrUnknownSource if the host is not registered, or an error if owner or name are invalid for the source.
+func (sr *SourceRegistry) validate(host, owner, name string) error {
+	source, ok := sr.Lookup(host)
+	if !ok {
+		return fmt.Errorf("%w: %q, register it in DefaultSources", ErrUnknownSource, host)
+	}
+	return source.validate(owner, name)
+}
+
+// validate owner and name, owners of GitLab and Azure DevOps consist of multiple segments separated by '/'.
+func (s Source) validate(owner, name string) error {
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("name %q of %s can't contain '/'", name, s.Host)
+	}
+	if owner == "" {
+		return nil
+	}
+	segments := strings.Split(owner, "/")
+	for _, segment := range segments {
+		if strings.TrimSpace(segment) == "" {
+			return fmt.Errorf("owner %q of %s has an empty segment", owner, s.Host)
+		}
+	}
+	switch s.Kind {
+	case SourceGitLab:
+		return nil
+	case SourceAzureDevOps:
+		if len(segments) != 2 {
+			return fmt.Errorf("owner %q of %s must be organization/project", owner, s.Host)
+		}
+	default:
+		if len(segments) != 1 {
+			return fmt.Errorf("owner %q of %s can't contain '/'", owner, s.Host)
+		}
+	}
+	return nil
+}
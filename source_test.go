@@ -0,0 +1,105 @@
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceRegistry(t *testing.T) {
+	shouldBe := assert.New(t)
+	registry := NewSourceRegistry(
+		Source{Host: "github.com", Name: "github"},
+		Source{Host: "bitbucket.org", Name: "bitbucket", Aliases: []string{"bitbucket.com"}},
+	)
+	shouldBe.Nil(registry.Register(Source{Host: "GitHub.Example.io", Name: "github-enterprise", Enterprise: true}))
+	tests := []struct {
+		host      string
+		canonical string
+		name      string
+		known     bool
+	}{
+		{host: "github.com", canonical: "github.com", name: "github", known: true},
+		{host: "bitbucket.com", canonical: "bitbucket.org", name: "bitbucket", known: true},
+		{host: "BitBucket.org", canonical: "bitbucket.org", name: "bitbucket", known: true},
+		{host: "github.example.io", canonical: "github.example.io", name: "github-enterprise", known: true},
+		{host: "gitlab.example.io", canonical: "gitlab.example.io", name: "gitlab.example.io"},
+		{host: "bitbucket.org.internal", canonical: "bitbucket.org.internal", name: "bitbucket.org.internal"},
+		{host: "code.company.com", canonical: "code.company.com", name: "code.company"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			_, known := registry.Lookup(tt.host)
+			assert.Equal(t, tt.known, known)
+			assert.Equal(t, tt.canonical, registry.Canonical(tt.host))
+			assert.Equal(t, tt.name, registry.Name(tt.host))
+		})
+	}
+}
+
+func TestSourceRegistry_Register_Invalid(t *testing.T) {
+	shouldBe := assert.New(t)
+	registry := NewSourceRegistry(Source{Host: "bitbucket.org", Name: "bitbucket", Aliases: []string{"bitbucket.com"}})
+	shouldBe.True(errors.Is(registry.Register(Source{Host: "bitbucket.com", Name: "other"}), ErrInvalidSource))
+	shouldBe.True(errors.Is(registry.Register(Source{Host: "not a host", Name: "invalid"}), ErrInvalidSource))
+	shouldBe.True(errors.Is(registry.Register(Source{Host: "example.com"}), ErrInvalidSource))
+	shouldBe.Nil(registry.Register(Source{Host: "bitbucket.org", Name: "bitbucket", Aliases: []string{"bitbucket.com", "bb.example.io"}}), "re-registering a source updates it")
+	shouldBe.Equal("bitbucket.org", registry.Canonical("bb.example.io"))
+}
+
+func TestRequest_FQN(t *testing.T) {
+	shouldBe := assert.New(t)
+	request := Request{FromSource: "bitbucket.com", FromOwner: "owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "repo1"}
+	shouldBe.Equal("bitbucket.org/owner1/repo1", request.FromFQN())
+	shouldBe.Equal("github.com/owner1/repo1", request.ToFQN())
+}
+
+func TestRequest_Validate_UnknownSource(t *testing.T) {
+	request := Request{FromSource: "gitlab.example.io", FromOwner: "owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "repo1"}
+	err := request.Validate()
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+	assert.True(t, errors.Is(err, ErrUnknownSource))
+}
+
+func TestRequest_FQN_NestedOwner(t *testing.T) {
+	shouldBe := assert.New(t)
+	request := Request{FromSource: "gitlab.com", FromOwner: "group/subgroup", FromName: "repo1", ToSource: "dev.azure.com", ToOwner: "organization/project", ToName: "repo1"}
+	shouldBe.Nil(request.Validate())
+	shouldBe.Equal("gitlab.com/group/subgroup/repo1", request.FromFQN())
+	shouldBe.Equal("dev.azure.com/organization/project/repo1", request.ToFQN())
+	shouldBe.Equal("gitlab", DefaultSources.Name(request.FromSource))
+	shouldBe.Equal("azure", DefaultSources.Name(request.ToSource))
+}
+
+func TestSource_Validate(t *testing.T) {
+	tests := []struct {
+		source Source
+		owner  string
+		name   string
+		valid  bool
+	}{
+		{source: Source{Host: "github.com", Kind: SourceGitHub}, owner: "owner1", name: "repo1", valid: true},
+		{source: Source{Host: "github.com", Kind: SourceGitHub}, owner: "owner1/sub", name: "repo1"},
+		{source: Source{Host: "bitbucket.org", Kind: SourceBitbucket}, owner: "owner1/sub", name: "repo1"},
+		{source: Source{Host: "github.example.io"}, owner: "owner1/sub", name: "repo1"},
+		{source: Source{Host: "gitlab.com", Kind: SourceGitLab}, owner: "group", name: "repo1", valid: true},
+		{source: Source{Host: "gitlab.com", Kind: SourceGitLab}, owner: "group/subgroup/team", name: "repo1", valid: true},
+		{source: Source{Host: "gitlab.com", Kind: SourceGitLab}, owner: "group//team", name: "repo1"},
+		{source: Source{Host: "gitlab.com", Kind: SourceGitLab}, owner: "group/", name: "repo1"},
+		{source: Source{Host: "gitlab.com", Kind: SourceGitLab}, owner: "group", name: "sub/repo1"},
+		{source: Source{Host: "dev.azure.com", Kind: SourceAzureDevOps}, owner: "organization/project", name: "repo1", valid: true},
+		{source: Source{Host: "dev.azure.com", Kind: SourceAzureDevOps}, owner: "organization", name: "repo1"},
+		{source: Source{Host: "dev.azure.com", Kind: SourceAzureDevOps}, owner: "organization/project/team", name: "repo1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source.Host+"/"+tt.owner+"/"+tt.name, func(t *testing.T) {
+			err := tt.source.validate(tt.owner, tt.name)
+			if tt.valid {
+				assert.Nil(t, err)
+			} else {
+				assert.NotNil(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLLeaser is a Leaser backed by database/sql, run MigrateSQL before using it.
+type SQLLeaser struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// NewSQLLeaser returns a Leaser using the given database, leases are shared by all workers using the same database.
+func NewSQLLeaser(db *sql.DB) *SQLLeaser {
+	return &SQLLeaser{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func (sl *SQLLeaser) Acquire(ctx context.Context, taskID, owner string, ttl time.Duration) (*Lease, error) {
+	now := sl.now().UTC()
+	lease := &Lease{TaskID: taskID, Owner: owner, ExpiresAt: now.Add(ttl)}
+	res, err := sl.db.ExecContext(ctx, `INSERT INTO migration_leases (task_id, owner, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (task_id) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE migration_leases.owner = excluded.owner OR migration_leases.expires_at <= $4`,
+		lease.TaskID, lease.Owner, lease.ExpiresAt, now)
+	if err != nil {
+		return nil, fmt.Errorf("sql leaser: error while acquiring lease of task %s: %w", taskID, err)
+	}
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("sql leaser: error while acquiring lease of task %s: %w", taskID, err)
+	}
+	if affected == 0 {
+		return nil, ErrLeaseHeld
+	}
+	return lease, nil
+}
+
+func (sl *SQLLeaser) Renew(ctx context.Context, lease *Lease, ttl time.Duration) error {
+	now := sl.now().UTC()
+	expiresAt := now.Add(ttl)
+	// an expired lease can't be renewed, its task may be reclaimed already
+	res, err := sl.db.ExecContext(ctx, `UPDATE migration_leases SET expires_at = $1 WHERE task_id = $2 AND owner = $3 AND expires_at > $4`, expiresAt, lease.TaskID, lease.Owner, now)
+	if err != nil {
+		return fmt.Errorf("sql leaser: error while renewing lease of task %s: %w", lease.TaskID, err)
+	}
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
+		return fmt.Errorf("sql leaser: error while renewing lease of task %s: %w", lease.TaskID, err)
+	}
+	if affected == 0 {
+		return ErrLeaseLost
+	}
+	lease.ExpiresAt = expiresAt
+	return nil
+}
+
+func (sl *SQLLeaser) Release(ctx context.Context, lease *Lease) error {
+	if _, err := sl.db.ExecContext(ctx, `DELETE FROM migration_leases WHERE task_id = $1 AND owner = $2`, lease.TaskID, lease.Owner); err != nil {
+		return fmt.Errorf("sql leaser: error while releasing lease of task %s: %w", lease.TaskID, err)
+	}
+	return nil
+}
+
+func (sl *SQLLeaser) Expired(ctx context.Context) ([]*Lease, error) {
+	rows, err := sl.db.QueryContext(ctx, `SELECT task_id, owner, expires_at FROM migration_leases WHERE expires_at <= $1`, sl.now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("sql leaser: error while listing expired leases: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	leases := make([]*Lease, 0)
+	for rows.Next() {
+		lease := &Lease{}
+		if err = rows.Scan(&lease.TaskID, &lease.Owner, &lease.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("sql leaser: error while listing expired leases: %w", err)
+		}
+		leases = append(leases, lease)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql leaser: error while listing expired leases: %w", err)
+	}
+	return leases, nil
+}
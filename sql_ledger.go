@@ -0,0 +1,63 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SQLLedger is a Ledger backed by database/sql, run MigrateSQL before using it.
+type SQLLedger struct {
+	db *sql.DB
+}
+
+// NewSQLLedger returns a Ledger using the given database, changes are kept next to the tasks of SQLStore.
+func NewSQLLedger(db *sql.DB) *SQLLedger {
+	return &SQLLedger{db: db}
+}
+
+func (sl *SQLLedger) Record(ctx context.Context, taskID string, entity EntityType, changes ...Change) error {
+	tx, err := sl.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sql ledger: error while recording %s changes of task %s: %w", entity, taskID, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, change := range changes {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO migration_ledger (task_id, entity, from_id, to_id) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (task_id, entity, from_id) DO UPDATE SET to_id = excluded.to_id`,
+			taskID, string(entity), change.FromID, change.ToID); err != nil {
+			return fmt.Errorf("sql ledger: error while recording %s change %d of task %s: %w", entity, change.FromID, taskID, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("sql ledger: error while recording %s changes of task %s: %w", entity, taskID, err)
+	}
+	return nil
+}
+
+func (sl *SQLLedger) Changes(ctx context.Context, taskID string, entity EntityType) ([]Change, error) {
+	rows, err := sl.db.QueryContext(ctx, `SELECT from_id, to_id FROM migration_ledger WHERE task_id = $1 AND entity = $2 ORDER BY from_id`, taskID, string(entity))
+	if err != nil {
+		return nil, fmt.Errorf("sql ledger: error while listing %s changes of task %s: %w", entity, taskID, err)
+	}
+	defer func() { _ = rows.Close() }()
+	changes := make([]Change, 0)
+	for rows.Next() {
+		change := Change{}
+		if err = rows.Scan(&change.FromID, &change.ToID); err != nil {
+			return nil, fmt.Errorf("sql ledger: error while listing %s changes of task %s: %w", entity, taskID, err)
+		}
+		changes = append(changes, change)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql ledger: error while listing %s changes of task %s: %w", entity, taskID, err)
+	}
+	return changes, nil
+}
+
+func (sl *SQLLedger) Clear(ctx context.Context, taskID string) error {
+	if _, err := sl.db.ExecContext(ctx, `DELETE FROM migration_ledger WHERE task_id = $1`, taskID); err != nil {
+		return fmt.Errorf("sql ledger: error while clearing changes of task %s: %w", taskID, err)
+	}
+	return nil
+}
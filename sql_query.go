@@ -0,0 +1,159 @@
+package migration
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrMissingSQLArg = fmt.Errorf("missing named sql argument")
+
+// SQLDialect determines the positional placeholders used by Bind.
+type SQLDialect string
+
+const (
+	// SQLPostgres uses $1, $2, ... and also applies to CockroachDB.
+	SQLPostgres SQLDialect = "postgres"
+	// SQLMySQL uses ?.
+	SQLMySQL SQLDialect = "mysql"
+	// SQLSQLite uses ?, it also accepts $1 like SQLStore uses.
+	SQLSQLite SQLDialect = "sqlite"
+	// SQLServer uses @p1, @p2, ...
+	SQLServer SQLDialect = "sqlserver"
+)
+
+// Bind rewrites the @name parameters of the query to the placeholders of the dialect and returns the values of the
+// named args in the order of the placeholders, like Bind(`UPDATE migration_tasks SET status = @status WHERE id = @id`, task.SqlArgs()...).
+// Parameters inside quoted literals and identifiers, comments, Postgres dollar-quoted strings and @@ variables are kept
+// as is, MySQL strings may escape quotes with a backslash. Unused args are ignored and a parameter without arg returns
+// ErrMissingSQLArg. A repeated parameter reuses its placeholder if the dialect numbers them.
+func (d SQLDialect) Bind(query string, args ...sql.NamedArg) (string, []any, error) {
+	switch d {
+	case SQLPostgres, SQLMySQL, SQLSQLite, SQLServer:
+	default:
+		return "", nil, fmt.Errorf("unsupported sql dialect %q", d)
+	}
+	values := make(map[string]any, len(args))
+	for _, arg := range args {
+		values[arg.Name] = arg.Value
+	}
+	positions := make(map[string]int)
+	bound := make([]any, 0, len(args))
+	var builder strings.Builder
+	builder.Grow(len(query))
+	for index := 0; index < len(query); index++ {
+		char := query[index]
+		switch {
+		case char == '\'' || char == '"' || char == '`':
+			end := _quoteEnd(query, index, d == SQLMySQL && char != '`')
+			builder.WriteString(query[index:end])
+			index = end - 1
+		case strings.HasPrefix(query[index:], "--") || d == SQLMySQL && char == '#':
+			end := len(query)
+			if newline := strings.IndexByte(query[index:], '\n'); newline != -1 {
+				end = index + newline + 1
+			}
+			builder.WriteString(query[index:end])
+			index = end - 1
+		case strings.HasPrefix(query[index:], "/*"):
+			end := len(query)
+			if closing := strings.Index(query[index+2:], "*/"); closing != -1 {
+				end = index + 2 + closing + 2
+			}
+			builder.WriteString(query[index:end])
+			index = end - 1
+		case d == SQLPostgres && char == '$' && _dollarTag(query[index:]) != "":
+			tag := _dollarTag(query[index:])
+			end := len(query)
+			if closing := strings.Index(query[index+len(tag):], tag); closing != -1 {
+				end = index + len(tag) + closing + len(tag)
+			}
+			builder.WriteString(query[index:end])
+			index = end - 1
+		case char == '@' && index+1 < len(query) && query[index+1] == '@':
+			end := index + 2
+			for end < len(query) && _isNameChar(query[end]) {
+				end++
+			}
+			builder.WriteString(query[index:end])
+			index = end - 1
+		case char == '@' && index+1 < len(query) && _isNameStart(query[index+1]):
+			end := index + 2
+			for end < len(query) && _isNameChar(query[end]) {
+				end++
+			}
+			name := query[index+1 : end]
+			value, ok := values[name]
+			if !ok {
+				return "", nil, fmt.Errorf("%w: @%s", ErrMissingSQLArg, name)
+			}
+			position, reused := positions[name]
+			if !reused || d == SQLMySQL || d == SQLSQLite {
+				bound = append(bound, value)
+				position = len(bound)
+				positions[name] = position
+			}
+			builder.WriteString(d.placeholder(position))
+			index = end - 1
+		default:
+			builder.WriteByte(char)
+		}
+	}
+	return builder.String(), bound, nil
+}
+
+func (d SQLDialect) placeholder(position int) string {
+	switch d {
+	case SQLPostgres:
+		return "$" + strconv.Itoa(position)
+	case SQLServer:
+		return "@p" + strconv.Itoa(position)
+	default:
+		return "?"
+	}
+}
+
+// _quoteEnd returns the index after the closing quote of the literal or identifier starting at start, doubled quotes
+// are escaped quotes and so are quotes following a backslash if backslash is set. The end of the query is returned
+// if the quote isn't closed.
+func _quoteEnd(query string, start int, backslash bool) int {
+	quote := query[start]
+	for index := start + 1; index < len(query); index++ {
+		if backslash && query[index] == '\\' {
+			index++
+			continue
+		}
+		if query[index] != quote {
+			continue
+		}
+		if index+1 < len(query) && query[index+1] == quote {
+			index++
+			continue
+		}
+		return index + 1
+	}
+	return len(query)
+}
+
+// _dollarTag returns the tag like $$ or $body$ if the query starts with a Postgres dollar quote, positional
+// parameters like $1 aren't tags.
+func _dollarTag(query string) string {
+	for index := 1; index < len(query); index++ {
+		switch {
+		case query[index] == '$':
+			return query[:index+1]
+		case index == 1 && !_isNameStart(query[index]), !_isNameChar(query[index]):
+			return ""
+		}
+	}
+	return ""
+}
+
+func _isNameStart(char byte) bool {
+	return char == '_' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z'
+}
+
+func _isNameChar(char byte) bool {
+	return _isNameStart(char) || char >= '0' && char <= '9'
+}
@@ -0,0 +1,101 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLDialect_Bind(t *testing.T) {
+	args := []sql.NamedArg{sql.Named("id", "1234"), sql.Named("status", "queued"), sql.Named("unused", 1)}
+	query := `UPDATE migration_tasks SET status = @status, error_details = '@id isn''t bound' WHERE id = @id OR from_name = @id AND "@status" = @@ROWCOUNT`
+	tests := []struct {
+		dialect SQLDialect
+		query   string
+		args    []any
+	}{
+		{SQLPostgres, `UPDATE migration_tasks SET status = $1, error_details = '@id isn''t bound' WHERE id = $2 OR from_name = $2 AND "@status" = @@ROWCOUNT`, []any{"queued", "1234"}},
+		{SQLMySQL, `UPDATE migration_tasks SET status = ?, error_details = '@id isn''t bound' WHERE id = ? OR from_name = ? AND "@status" = @@ROWCOUNT`, []any{"queued", "1234", "1234"}},
+		{SQLSQLite, `UPDATE migration_tasks SET status = ?, error_details = '@id isn''t bound' WHERE id = ? OR from_name = ? AND "@status" = @@ROWCOUNT`, []any{"queued", "1234", "1234"}},
+		{SQLServer, `UPDATE migration_tasks SET status = @p1, error_details = '@id isn''t bound' WHERE id = @p2 OR from_name = @p2 AND "@status" = @@ROWCOUNT`, []any{"queued", "1234"}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.dialect), func(t *testing.T) {
+			shouldBe := assert.New(t)
+			bound, values, err := test.dialect.Bind(query, args...)
+			shouldBe.Nil(err)
+			shouldBe.Equal(test.query, bound)
+			shouldBe.Equal(test.args, values)
+		})
+	}
+}
+
+func TestSQLDialect_Bind_skipped(t *testing.T) {
+	args := []sql.NamedArg{sql.Named("id", "1234")}
+	tests := []struct {
+		name    string
+		dialect SQLDialect
+		query   string
+		bound   string
+	}{
+		{"mysql backslash", SQLMySQL, `SELECT 'it\'s @x' FROM t WHERE id = @id`, `SELECT 'it\'s @x' FROM t WHERE id = ?`},
+		{"mysql backslash double quote", SQLMySQL, `SELECT "say \"@x\"" FROM t WHERE id = @id`, `SELECT "say \"@x\"" FROM t WHERE id = ?`},
+		{"mysql hash comment", SQLMySQL, "SELECT 1 # @x\nWHERE id = @id", "SELECT 1 # @x\nWHERE id = ?"},
+		{"postgres backslash", SQLPostgres, `SELECT '\' WHERE id = @id`, `SELECT '\' WHERE id = $1`},
+		{"line comment", SQLPostgres, "SELECT 1 -- @x\nWHERE id = @id", "SELECT 1 -- @x\nWHERE id = $1"},
+		{"line comment at end", SQLServer, "SELECT 1 WHERE id = @id -- @x", "SELECT 1 WHERE id = @p1 -- @x"},
+		{"block comment", SQLSQLite, "SELECT /* @x\n@y */ 1 WHERE id = @id", "SELECT /* @x\n@y */ 1 WHERE id = ?"},
+		{"block comment mysql", SQLMySQL, "SELECT /* it's @x */ 1 WHERE id = @id", "SELECT /* it's @x */ 1 WHERE id = ?"},
+		{"dollar quote", SQLPostgres, "SELECT $$ it's @x $$ WHERE id = @id", "SELECT $$ it's @x $$ WHERE id = $1"},
+		{"dollar quote tag", SQLPostgres, "SELECT $body$ $$ @x $body$ WHERE id = @id", "SELECT $body$ $$ @x $body$ WHERE id = $1"},
+		{"positional is no dollar quote", SQLPostgres, "SELECT $1 WHERE id = @id", "SELECT $1 WHERE id = $1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shouldBe := assert.New(t)
+			bound, values, err := test.dialect.Bind(test.query, args...)
+			shouldBe.Nil(err)
+			shouldBe.Equal(test.bound, bound)
+			shouldBe.Equal([]any{"1234"}, values)
+		})
+	}
+}
+
+func TestSQLDialect_Bind_errors(t *testing.T) {
+	shouldBe := assert.New(t)
+	_, _, err := SQLPostgres.Bind(`SELECT * FROM migration_tasks WHERE id = @id`, sql.Named("status", "queued"))
+	shouldBe.ErrorIs(err, ErrMissingSQLArg)
+	shouldBe.ErrorContains(err, "@id")
+	_, _, err = SQLDialect("oracle").Bind(`SELECT 1`)
+	shouldBe.ErrorContains(err, "unsupported sql dialect")
+	bound, values, err := SQLPostgres.Bind(`SELECT 'unterminated @id`, sql.Named("id", "1234"))
+	shouldBe.Nil(err)
+	shouldBe.Equal(`SELECT 'unterminated @id`, bound)
+	shouldBe.Empty(values)
+}
+
+func TestSQLDialect_Bind_taskSqlArgs(t *testing.T) {
+	shouldBe := assert.New(t)
+	ctx := context.TODO()
+	db := _SQLiteDB(t)
+	store := NewSQLStore(db)
+	task := _WaitingTask()
+	task.ID = ""
+	shouldBe.Nil(store.Create(ctx, task))
+	task.Status = StatusInProgress
+	task.LastStep = StepBuildsDone
+	task.Builds = 42
+	for _, dialect := range []SQLDialect{SQLSQLite, SQLPostgres} {
+		query, values, err := dialect.Bind(`UPDATE migration_tasks SET status = @status, last_step = @lastStep, builds = @builds, checkpoints = @checkpoints WHERE id = @id`, task.SqlArgs()...)
+		shouldBe.Nil(err)
+		_, err = db.ExecContext(ctx, query, values...)
+		shouldBe.Nil(err)
+		stored, err := store.Get(ctx, task.ID)
+		shouldBe.Nil(err)
+		shouldBe.Equal(StatusInProgress, stored.Status)
+		shouldBe.Equal(StepBuildsDone, stored.LastStep)
+		shouldBe.Equal(42, stored.Builds)
+	}
+}
@@ -0,0 +1,293 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// sqlMigrations are applied in order by MigrateSQL, append new migrations and never modify existing ones.
+// Statements are compatible with Postgres, CockroachDB and SQLite.
+var sqlMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS migration_tasks (
+		id             VARCHAR(64) PRIMARY KEY,
+		from_source    VARCHAR(255) NOT NULL,
+		from_owner     VARCHAR(255) NOT NULL,
+		from_name      VARCHAR(255) NOT NULL,
+		to_source      VARCHAR(255) NOT NULL,
+		to_owner       VARCHAR(255) NOT NULL,
+		to_name        VARCHAR(255) NOT NULL,
+		callback_url   TEXT,
+		restart        VARCHAR(64) NOT NULL DEFAULT '',
+		status         VARCHAR(32) NOT NULL,
+		last_step      VARCHAR(64) NOT NULL,
+		builds         INT NOT NULL DEFAULT 0,
+		releases       INT NOT NULL DEFAULT 0,
+		total_duration BIGINT NOT NULL DEFAULT 0,
+		error_details  TEXT,
+		queued_at      TIMESTAMP NOT NULL,
+		updated_at     TIMESTAMP NOT NULL,
+		version        BIGINT NOT NULL DEFAULT 1
+	)`,
+	`CREATE INDEX IF NOT EXISTS migration_tasks_status_queued_at ON migration_tasks (status, queued_at)`,
+	`CREATE TABLE IF NOT EXISTS migration_leases (
+		task_id    VARCHAR(64) PRIMARY KEY,
+		owner      VARCHAR(255) NOT NULL,
+		expires_at TIMESTAMP NOT NULL
+	)`,
+	`ALTER TABLE migration_tasks ADD COLUMN progress TEXT`,
+	`ALTER TABLE migration_tasks ADD COLUMN history TEXT`,
+	`ALTER TABLE migration_tasks ADD COLUMN stage_durations TEXT`,
+	`ALTER TABLE migration_tasks ADD COLUMN dry_run BOOLEAN NOT NULL DEFAULT FALSE`,
+	`ALTER TABLE migration_tasks ADD COLUMN plan TEXT`,
+	`CREATE TABLE IF NOT EXISTS migration_ledger (
+		task_id VARCHAR(64) NOT NULL,
+		entity  VARCHAR(32) NOT NULL,
+		from_id BIGINT NOT NULL,
+		to_id   BIGINT NOT NULL,
+		PRIMARY KEY (task_id, entity, from_id)
+	)`,
+	`ALTER TABLE migration_tasks ADD COLUMN checkpoints TEXT`,
+}
+
+const sqlTaskColumns = `id, from_source, from_owner, from_name, to_source, to_owner, to_name, callback_url, restart, status, last_step, builds, releases, total_duration, error_details, queued_at, updated_at, version, progress, history, stage_durations, dry_run, plan, checkpoints`
+
+// MigrateSQL applies missing schema migrations used by SQLStore, SQLLeaser and SQLLedger, it is safe to call on every
+// start and concurrently from multiple instances. Each migration records its version before it is applied in the same
+// transaction, so a concurrent call waits on the version row and skips a migration applied by another call.
+func MigrateSQL(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS migration_schema (version INT PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("error while creating schema table: %w", err)
+	}
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM migration_schema`).Scan(&current); err != nil {
+		return fmt.Errorf("error while reading schema version: %w", err)
+	}
+	for version := current + 1; version <= len(sqlMigrations); version++ {
+		applied, err := _migrate(ctx, db, version)
+		if err != nil {
+			return err
+		}
+		if applied {
+			log.Info().Str("module", "github.com/estafette/migration").Int("version", version).Msg("applied schema migration")
+		}
+	}
+	return nil
+}
+
+// _migrate applies the migration with the given version and returns false if it was already applied by another call.
+func _migrate(ctx context.Context, db *sql.DB, version int) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error while starting schema migration %d: %w", version, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	// the version is recorded first, a concurrent migration of the same version blocks on it until this one is done
+	if _, err = tx.ExecContext(ctx, `INSERT INTO migration_schema (version) VALUES ($1)`, version); err != nil {
+		_ = tx.Rollback()
+		if recorded, recordedErr := _migrated(ctx, db, version); recordedErr == nil && recorded {
+			return false, nil
+		}
+		return false, fmt.Errorf("error while recording schema migration %d: %w", version, err)
+	}
+	if _, err = tx.ExecContext(ctx, sqlMigrations[version-1]); err != nil {
+		return false, fmt.Errorf("error while applying schema migration %d: %w", version, err)
+	}
+	if err = tx.Commit(); err != nil {
+		if recorded, recordedErr := _migrated(ctx, db, version); recordedErr == nil && recorded {
+			return false, nil
+		}
+		return false, fmt.Errorf("error while committing schema migration %d: %w", version, err)
+	}
+	return true, nil
+}
+
+// _migrated returns true if the migration with the given version is recorded.
+func _migrated(ctx context.Context, db *sql.DB, version int) (bool, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM migration_schema WHERE version = $1`, version).Scan(&count); err != nil {
+		return false, fmt.Errorf("error while reading schema migration %d: %w", version, err)
+	}
+	return count > 0, nil
+}
+
+// SQLStore is a Store backed by database/sql, run MigrateSQL before using it.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore returns a Store using the given database.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) Create(ctx context.Context, task *Task) error {
+	if task.ID == "" {
+		task.ID = uuid.NewString()
+	}
+	now := time.Now().UTC()
+	if task.QueuedAt.IsZero() {
+		task.QueuedAt = now
+	}
+	task.UpdatedAt = now
+	task.Version = 1
+	_, err := s.db.ExecContext(ctx, `INSERT INTO migration_tasks (`+sqlTaskColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)`,
+		task.ID, task.FromSource, task.FromOwner, task.FromName, task.ToSource, task.ToOwner, task.ToName, task.CallbackURL, string(task.Restart),
+		task.Status.String(), task.LastStep.String(), task.Builds, task.Releases, int64(task.TotalDuration), task.ErrorDetails,
+		task.QueuedAt.UTC(), task.UpdatedAt, task.Version, jsonColumn{&task.Progress}, jsonColumn{&task.History}, jsonColumn{&task.StageDurations}, task.DryRun, jsonColumn{&task.Plan}, jsonColumn{&task.Checkpoints})
+	if err != nil {
+		return fmt.Errorf("sql store: error while creating task %s: %w", task.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLStore) Get(ctx context.Context, taskID string) (*Task, error) {
+	task, err := _scanTask(s.db.QueryRowContext(ctx, `SELECT `+sqlTaskColumns+` FROM migration_tasks WHERE id = $1`, taskID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sql store: error while reading task %s: %w", taskID, err)
+	}
+	return task, nil
+}
+
+func (s *SQLStore) Update(ctx context.Context, task *Task) error {
+	updatedAt := time.Now().UTC()
+	res, err := s.db.ExecContext(ctx, `UPDATE migration_tasks SET
+		callback_url = $1, restart = $2, status = $3, last_step = $4, builds = $5, releases = $6,
+		total_duration = $7, error_details = $8, updated_at = $9, progress = $10, history = $11, stage_durations = $12, plan = $13, checkpoints = $14,
+		version = version + 1 WHERE id = $15 AND version = $16`,
+		task.CallbackURL, string(task.Restart), task.Status.String(), task.LastStep.String(), task.Builds, task.Releases,
+		int64(task.TotalDuration), task.ErrorDetails, updatedAt, jsonColumn{&task.Progress}, jsonColumn{&task.History}, jsonColumn{&task.StageDurations}, jsonColumn{&task.Plan},
+		jsonColumn{&task.Checkpoints}, task.ID, task.Version)
+	if err != nil {
+		return fmt.Errorf("sql store: error while updating task %s: %w", task.ID, err)
+	}
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
+		return fmt.Errorf("sql store: error while updating task %s: %w", task.ID, err)
+	}
+	if affected == 0 {
+		if _, err = s.Get(ctx, task.ID); err != nil {
+			return err
+		}
+		return ErrTaskConflict
+	}
+	task.Version++
+	task.UpdatedAt = updatedAt
+	return nil
+}
+
+func (s *SQLStore) List(ctx context.Context, status Status) ([]*Task, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+sqlTaskColumns+` FROM migration_tasks WHERE status = $1 ORDER BY queued_at`, status.String())
+	if err != nil {
+		return nil, fmt.Errorf("sql store: error while listing %s tasks: %w", status.String(), err)
+	}
+	defer func() { _ = rows.Close() }()
+	tasks := make([]*Task, 0)
+	for rows.Next() {
+		var task *Task
+		if task, err = _scanTask(rows); err != nil {
+			return nil, fmt.Errorf("sql store: error while listing %s tasks: %w", status.String(), err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("sql store: error while listing %s tasks: %w", status.String(), err)
+	}
+	return tasks, nil
+}
+
+// ClaimNext uses compare-and-swap on the task version, so concurrent workers never claim the same task.
+func (s *SQLStore) ClaimNext(ctx context.Context) (*Task, error) {
+	queued := StatusQueued
+	inProgress := StatusInProgress
+	for {
+		var taskID string
+		var version int64
+		err := s.db.QueryRowContext(ctx, `SELECT id, version FROM migration_tasks WHERE status = $1 ORDER BY queued_at LIMIT 1`, queued.String()).Scan(&taskID, &version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoQueuedTasks
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sql store: error while claiming task: %w", err)
+		}
+		var res sql.Result
+		res, err = s.db.ExecContext(ctx, `UPDATE migration_tasks SET status = $1, updated_at = $2, version = version + 1 WHERE id = $3 AND version = $4 AND status = $5`,
+			inProgress.String(), time.Now().UTC(), taskID, version, queued.String())
+		if err != nil {
+			return nil, fmt.Errorf("sql store: error while claiming task %s: %w", taskID, err)
+		}
+		var affected int64
+		if affected, err = res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("sql store: error while claiming task %s: %w", taskID, err)
+		}
+		if affected == 1 {
+			return s.Get(ctx, taskID)
+		}
+		log.Debug().Str("module", "github.com/estafette/migration").Str("taskID", taskID).Msg("task claimed by another worker, retrying")
+	}
+}
+
+type _scanner interface {
+	Scan(dest ...any) error
+}
+
+func _scanTask(row _scanner) (*Task, error) {
+	task := &Task{}
+	err := row.Scan(&task.ID, &task.FromSource, &task.FromOwner, &task.FromName, &task.ToSource, &task.ToOwner, &task.ToName, &task.CallbackURL, &task.Restart,
+		&task.Status, &task.LastStep, &task.Builds, &task.Releases, &task.TotalDuration, &task.ErrorDetails,
+		&task.QueuedAt, &task.UpdatedAt, &task.Version, jsonColumn{&task.Progress}, jsonColumn{&task.History}, jsonColumn{&task.StageDurations}, &task.DryRun, jsonColumn{&task.Plan}, jsonColumn{&task.Checkpoints})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// jsonColumn scans a nullable JSON column into the value pointed to by dest and writes the value pointed to by dest
+// as JSON, or NULL if it is nil or empty. A value which can't be encoded fails the write.
+type jsonColumn struct {
+	dest any
+}
+
+func (jc jsonColumn) Value() (driver.Value, error) {
+	rv := reflect.ValueOf(jc.dest)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		if rv.IsNil() || (rv.Kind() != reflect.Pointer && rv.Len() == 0) {
+			return nil, nil
+		}
+	}
+	data, err := json.Marshal(rv.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("unsupported Value, encoding %T as JSON column: %w", rv.Interface(), err)
+	}
+	return string(data), nil
+}
+
+func (jc jsonColumn) Scan(src any) error {
+	switch val := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(val), jc.dest)
+	case []byte:
+		return json.Unmarshal(val, jc.dest)
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into JSON column", src)
+	}
+}
@@ -0,0 +1,272 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func _SQLiteDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migration.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err = MigrateSQL(context.TODO(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrateSQL(t *testing.T) {
+	shouldBe := assert.New(t)
+	db := _SQLiteDB(t)
+	shouldBe.Nil(MigrateSQL(context.TODO(), db))
+	var version int
+	shouldBe.Nil(db.QueryRow(`SELECT MAX(version) FROM migration_schema`).Scan(&version))
+	shouldBe.Equal(len(sqlMigrations), version)
+}
+
+func TestMigrateSQL_Concurrent(t *testing.T) {
+	shouldBe := assert.New(t)
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migration.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- MigrateSQL(context.TODO(), db) }()
+	}
+	for i := 0; i < cap(errs); i++ {
+		shouldBe.Nil(<-errs)
+	}
+	var count int
+	shouldBe.Nil(db.QueryRow(`SELECT COUNT(*) FROM migration_schema`).Scan(&count))
+	shouldBe.Equal(len(sqlMigrations), count)
+}
+
+func TestMigrateSQL_AlreadyApplied(t *testing.T) {
+	shouldBe := assert.New(t)
+	db := _SQLiteDB(t)
+	// a migration applied by a concurrent call after the schema version was read, its ALTER TABLE would fail
+	applied, err := _migrate(context.TODO(), db, len(sqlMigrations))
+	shouldBe.Nil(err)
+	shouldBe.False(applied)
+}
+
+func TestSQLStore_CreateGetUpdate(t *testing.T) {
+	shouldBe := assert.New(t)
+	ctx := context.TODO()
+	store := NewSQLStore(_SQLiteDB(t))
+	callback := "http://localhost:8080"
+	task := _WaitingTask()
+	task.ID = ""
+	task.CallbackURL = &callback
+	task.DryRun = true
+	task.Plan = &Plan{Changes: Changes{Builds: 10}, Existing: Changes{Builds: 1}}
+	task.QueuedAt = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	shouldBe.Nil(store.Create(ctx, task))
+	shouldBe.NotEmpty(task.ID)
+	shouldBe.Equal(int64(1), task.Version)
+
+	stored, err := store.Get(ctx, task.ID)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(task, stored)
+	}
+
+	errorDetails := "test error"
+	stored.Status = StatusFailed
+	stored.LastStep = StepBuildsFailed
+	stored.Releases = 10
+	stored.TotalDuration = 12 * time.Second
+	stored.ErrorDetails = &errorDetails
+	stored.History = []HistoryEntry{{Stage: BuildsStage, Step: StepBuildsFailed, Status: StatusFailed, At: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Duration: time.Second, Error: &errorDetails, WorkerID: "worker-1"}}
+	stored.StageDurations = map[StageName]*StageDuration{BuildsStage: {StartedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), FinishedAt: time.Date(2020, 1, 1, 0, 0, 1, 0, time.UTC), Duration: time.Second, Attempts: 1, Failures: 1}}
+	stored.Progress = &Progress{Stage: BuildsStage, Processed: 5, Total: 10, Current: "build-5", UpdatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	stored.Checkpoints = map[StageName]string{BuildsStage: "5"}
+	shouldBe.Nil(store.Update(ctx, stored))
+	shouldBe.Equal(int64(2), stored.Version)
+	updated, err := store.Get(ctx, task.ID)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal(stored, updated)
+	}
+
+	shouldBe.True(errors.Is(store.Update(ctx, task), ErrTaskConflict))
+	_, err = store.Get(ctx, "unknown")
+	shouldBe.True(errors.Is(err, ErrTaskNotFound))
+	shouldBe.True(errors.Is(store.Update(ctx, &Task{Request: Request{ID: "unknown"}}), ErrTaskNotFound))
+}
+
+func TestSQLStore_List(t *testing.T) {
+	shouldBe := assert.New(t)
+	ctx := context.TODO()
+	store := NewSQLStore(_SQLiteDB(t))
+	for i, status := range []Status{StatusQueued, StatusFailed, StatusQueued} {
+		task := _WaitingTask()
+		task.ID = ""
+		task.Status = status
+		task.QueuedAt = time.Date(2020, 1, 1, i, 0, 0, 0, time.UTC)
+		shouldBe.Nil(store.Create(ctx, task))
+	}
+	queued, err := store.List(ctx, StatusQueued)
+	if shouldBe.Nil(err) && shouldBe.Len(queued, 2) {
+		shouldBe.True(queued[0].QueuedAt.Before(queued[1].QueuedAt))
+	}
+	completed, err := store.List(ctx, StatusCompleted)
+	shouldBe.Nil(err)
+	shouldBe.Empty(completed)
+}
+
+func TestSQLStore_ClaimNext(t *testing.T) {
+	shouldBe := assert.New(t)
+	ctx := context.TODO()
+	store := NewSQLStore(_SQLiteDB(t))
+	for i := 0; i < 5; i++ {
+		task := _WaitingTask()
+		task.ID = ""
+		shouldBe.Nil(store.Create(ctx, task))
+	}
+	claimed := make(chan string, 10)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task, err := store.ClaimNext(ctx)
+			if err == nil {
+				shouldBe.Equal(StatusInProgress, task.Status)
+				claimed <- task.ID
+				return
+			}
+			shouldBe.True(errors.Is(err, ErrNoQueuedTasks), err)
+		}()
+	}
+	wg.Wait()
+	close(claimed)
+	ids := map[string]bool{}
+	for id := range claimed {
+		shouldBe.False(ids[id], "task %s claimed twice", id)
+		ids[id] = true
+	}
+	shouldBe.Len(ids, 5)
+}
+
+// _SQLRoundTrip writes value as integer using its Value, as text and as blob using its name and scans each column back.
+func _SQLRoundTrip(t *testing.T, db *sql.DB, value driver.Valuer, name string, scan func() sql.Scanner) []sql.Scanner {
+	if _, err := db.Exec(`DELETE FROM round_trip`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO round_trip (value_int, value_text, value_blob) VALUES ($1, $2, $3)`, value, name, []byte(name)); err != nil {
+		t.Fatal(err)
+	}
+	scanned := []sql.Scanner{scan(), scan(), scan()}
+	if err := db.QueryRow(`SELECT value_int, value_text, value_blob FROM round_trip`).Scan(scanned[0], scanned[1], scanned[2]); err != nil {
+		t.Fatal(err)
+	}
+	return scanned
+}
+
+func _RoundTripDB(t *testing.T) *sql.DB {
+	db := _SQLiteDB(t)
+	if _, err := db.Exec(`CREATE TABLE round_trip (value_int INTEGER, value_text TEXT, value_blob BLOB)`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestStatus_SQLRoundTrip(t *testing.T) {
+	db := _RoundTripDB(t)
+	statuses := make([]Status, 0)
+	for status := StatusUnset; status < 100; status++ {
+		if name := status.String(); name != "" && name != "unknown" {
+			statuses = append(statuses, status)
+		}
+	}
+	assert.Len(t, statuses, 5)
+	for _, status := range statuses {
+		status := status
+		t.Run(status.String(), func(t *testing.T) {
+			shouldBe := assert.New(t)
+			for _, scanned := range _SQLRoundTrip(t, db, &status, status.String(), func() sql.Scanner { return new(Status) }) {
+				shouldBe.Equal(status, *scanned.(*Status))
+			}
+		})
+	}
+}
+
+func TestStep_SQLRoundTrip(t *testing.T) {
+	db := _RoundTripDB(t)
+	steps := make([]Step, 0)
+	for step := StepWaiting; step < 1000; step++ {
+		if step.String() != "unknown" {
+			steps = append(steps, step)
+		}
+	}
+	assert.Len(t, steps, 27)
+	for _, step := range steps {
+		step := step
+		t.Run(step.String(), func(t *testing.T) {
+			shouldBe := assert.New(t)
+			for _, scanned := range _SQLRoundTrip(t, db, &step, step.String(), func() sql.Scanner { return new(Step) }) {
+				shouldBe.Equal(step, *scanned.(*Step))
+			}
+		})
+	}
+}
+
+func TestStatusStep_SQLScanUnknown(t *testing.T) {
+	db := _RoundTripDB(t)
+	tests := []struct {
+		name  string
+		query string
+		scan  sql.Scanner
+		err   error
+	}{
+		{"status integer", `SELECT 42`, new(Status), ErrUnknownStatus},
+		{"status text", `SELECT 'paused'`, new(Status), ErrUnknownStatus},
+		{"status blob", `SELECT CAST('paused' AS BLOB)`, new(Status), ErrUnknownStatus},
+		{"status integer text", `SELECT '3'`, new(Status), nil},
+		{"step integer", `SELECT 1000`, new(Step), ErrUnknownStep},
+		{"step text", `SELECT 'artifacts_done'`, new(Step), ErrUnknownStep},
+		{"step blob", `SELECT CAST('artifacts_done' AS BLOB)`, new(Step), ErrUnknownStep},
+		{"step integer blob", `SELECT CAST('42' AS BLOB)`, new(Step), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := db.QueryRow(test.query).Scan(test.scan)
+			if test.err == nil {
+				assert.Nil(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
+
+func TestJsonColumn_Value(t *testing.T) {
+	shouldBe := assert.New(t)
+	var plan *Plan
+	value, err := jsonColumn{&plan}.Value()
+	shouldBe.Nil(err)
+	shouldBe.Nil(value)
+	checkpoints := map[StageName]string{}
+	value, err = jsonColumn{&checkpoints}.Value()
+	shouldBe.Nil(err)
+	shouldBe.Nil(value)
+	checkpoints[BuildsStage] = "42"
+	value, err = jsonColumn{&checkpoints}.Value()
+	shouldBe.Nil(err)
+	shouldBe.Equal(`{"builds":"42"}`, value)
+	invalid := map[string]any{"func": func() {}}
+	_, err = jsonColumn{&invalid}.Value()
+	shouldBe.ErrorContains(err, "JSON column")
+}
@@ -2,6 +2,7 @@ package migration
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -33,20 +34,67 @@ func (s *stage) Failure() Step {
 	return s.failure
 }
 
-// Execute stage and return changes and whether to stop execution
+// Execute stage and return changes and whether to stop execution.
+// If Request.DryRun is set the task Step is not changed and executors add projected changes to Task.Plan, see dryRun.
 func (s *stage) Execute(ctx context.Context, task *Task) bool {
+	if task.DryRun {
+		return s.dryRun(ctx, task)
+	}
 	start := time.Now()
-	task.LastStep = s.Success()
+	if err := task.Transition(StatusInProgress, s.Success()); err != nil {
+		s.fail(ctx, task, start, err)
+		return false
+	}
+	stop := ProgressFrom(ctx).heartbeat(ctx)
 	err := s.execute(ctx, task)
+	stop()
+	if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		s.interrupt(ctx, task, start, err)
+		return false
+	}
 	if err != nil {
-		task.Status = StatusFailed
-		task.LastStep = s.Failure()
-		errorDetails := err.Error()
-		task.ErrorDetails = &errorDetails
-		log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Str("stage", string(s.Name())).Msg("stage failed")
+		if transitionErr := task.Transition(StatusFailed, s.Failure()); transitionErr != nil {
+			err = errors.Join(err, transitionErr)
+		}
+		s.fail(ctx, task, start, err)
 		return false
 	}
 	// in update query duration is appended to existing value
 	task.TotalDuration += time.Since(start)
+	delete(task.Checkpoints, s.Name())
+	s.record(ctx, task, start, nil)
 	return true
 }
+
+func (s *stage) fail(ctx context.Context, task *Task, start time.Time, err error) {
+	errorDetails := err.Error()
+	task.ErrorDetails = &errorDetails
+	s.record(ctx, task, start, &errorDetails)
+	log.Error().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Str("stage", string(s.Name())).Msg("stage failed")
+}
+
+// interrupt the stage of which the executor stopped because ctx is done, the task stays in progress at the failed step
+// of the stage so it can be queued again and the stage is executed again.
+func (s *stage) interrupt(ctx context.Context, task *Task, start time.Time, err error) {
+	if transitionErr := task.Transition(StatusInProgress, s.Failure()); transitionErr != nil {
+		err = errors.Join(err, transitionErr)
+	}
+	errorDetails := err.Error()
+	s.record(ctx, task, start, &errorDetails)
+	log.Warn().Str("module", "github.com/estafette/migration").Err(err).Str("taskID", task.ID).Str("stage", string(s.Name())).Msg("stage interrupted")
+}
+
+// record the stage attempt in the task history and stage durations.
+func (s *stage) record(ctx context.Context, task *Task, start time.Time, errorDetails *string) {
+	end := time.Now()
+	task.recordStageDuration(s.Name(), start, end, errorDetails != nil)
+	task.History = append(task.History, HistoryEntry{
+		Stage:    s.Name(),
+		Step:     task.LastStep,
+		Status:   task.Status,
+		At:       start,
+		Duration: end.Sub(start),
+		Error:    errorDetails,
+		WorkerID: WorkerIDFrom(ctx),
+	})
+}
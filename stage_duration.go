@@ -0,0 +1,47 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// StageDuration of all attempts to execute a stage, including failed attempts.
+type StageDuration struct {
+	// StartedAt of the last attempt.
+	StartedAt time.Time `json:"startedAt"`
+	// FinishedAt of the last attempt.
+	FinishedAt time.Time `json:"finishedAt"`
+	// Duration of all attempts.
+	Duration time.Duration `json:"duration"`
+	Attempts int           `json:"attempts"`
+	Failures int           `json:"failures,omitempty"`
+}
+
+// recordStageDuration adds an attempt of the stage to Task.StageDurations.
+func (t *Task) recordStageDuration(name StageName, start, end time.Time, failed bool) {
+	if t.StageDurations == nil {
+		t.StageDurations = map[StageName]*StageDuration{}
+	}
+	sd, ok := t.StageDurations[name]
+	if !ok {
+		sd = &StageDuration{}
+		t.StageDurations[name] = sd
+	}
+	sd.StartedAt = start
+	sd.FinishedAt = end
+	sd.Duration += end.Sub(start)
+	sd.Attempts++
+	if failed {
+		sd.Failures++
+	}
+}
+
+// _stageDurationsDict summarizes Task.StageDurations for logging.
+func _stageDurationsDict(task *Task) *zerolog.Event {
+	dict := zerolog.Dict()
+	for name, sd := range task.StageDurations {
+		dict = dict.Dur(string(name), sd.Duration)
+	}
+	return dict
+}
@@ -11,6 +11,8 @@ const (
 	BuildLogObjectsStage   StageName = "build_log_objects"
 	BuildVersionsStage     StageName = "build_versions"
 	ComputedTablesStage    StageName = "computed_tables"
+	VerificationStage      StageName = "verification"
+	LogChecksumsStage      StageName = "log_checksums"
 	ArchiveStage           StageName = "archive"
 	CallbackStage          StageName = "callback"
 	CompletedStage         StageName = "completed"
@@ -18,6 +20,21 @@ const (
 
 type StageName string
 
+// stageNames in order of execution.
+var stageNames = []StageName{ReleasesStage, ReleaseLogsStage, ReleaseLogObjectsStage, BuildsStage, BuildLogsStage, BuildLogObjectsStage,
+	BuildVersionsStage, ComputedTablesStage, VerificationStage, LogChecksumsStage, ArchiveStage, CallbackStage, CompletedStage}
+
+// IsValid returns true for LastStage and the predefined stage names.
+func (sn StageName) IsValid() bool {
+	switch sn {
+	case LastStage, ReleasesStage, ReleaseLogsStage, ReleaseLogObjectsStage, BuildsStage, BuildLogsStage, BuildLogObjectsStage,
+		BuildVersionsStage, ComputedTablesStage, VerificationStage, LogChecksumsStage, ArchiveStage, CallbackStage, CompletedStage:
+		return true
+	default:
+		return false
+	}
+}
+
 // SuccessStep for this stage name
 func (sn StageName) SuccessStep() Step {
 	switch sn {
@@ -37,6 +54,10 @@ func (sn StageName) SuccessStep() Step {
 		return StepBuildVersionsDone
 	case ComputedTablesStage:
 		return StepComputedTablesDone
+	case VerificationStage:
+		return StepVerificationDone
+	case LogChecksumsStage:
+		return StepLogChecksumsDone
 	case ArchiveStage:
 		return StepArchiveDone
 	case CallbackStage:
@@ -67,6 +88,10 @@ func (sn StageName) FailedStep() Step {
 		return StepBuildVersionsFailed
 	case ComputedTablesStage:
 		return StepComputedTablesFailed
+	case VerificationStage:
+		return StepVerificationFailed
+	case LogChecksumsStage:
+		return StepLogChecksumsFailed
 	case ArchiveStage:
 		return StepArchiveFailed
 	case CallbackStage:
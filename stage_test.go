@@ -39,6 +39,13 @@ func TestStage_Execute_Success(t *testing.T) {
 	shouldBe.True(result)
 	shouldBe.Equal(Step(0), task.LastStep)
 	shouldBe.GreaterOrEqual(task.TotalDuration, 50*time.Millisecond)
+	if shouldBe.Len(task.History, 1) {
+		shouldBe.Equal(StageName("test"), task.History[0].Stage)
+		shouldBe.Equal(Step(0), task.History[0].Step)
+		shouldBe.Equal(StatusInProgress, task.History[0].Status)
+		shouldBe.GreaterOrEqual(task.History[0].Duration, 50*time.Millisecond)
+		shouldBe.Nil(task.History[0].Error)
+	}
 }
 
 func TestStage_Execute_Failure(t *testing.T) {
@@ -53,11 +60,40 @@ func TestStage_Execute_Failure(t *testing.T) {
 		execute: mockedExecutor.execute,
 	}
 	task := &Task{Request: Request{ID: "test-123"}}
-	result := s.Execute(context.TODO(), task)
+	result := s.Execute(ContextWithWorkerID(context.TODO(), "worker-1"), task)
 	mockedExecutor.AssertExpectations(t)
 	shouldBe.False(result)
 	shouldBe.Equal(Step(1), task.LastStep)
+	if shouldBe.Len(task.History, 1) {
+		shouldBe.Equal(Step(1), task.History[0].Step)
+		shouldBe.Equal(StatusFailed, task.History[0].Status)
+		shouldBe.Equal(&expected, task.History[0].Error)
+		shouldBe.Equal("worker-1", task.History[0].WorkerID)
+	}
+	if shouldBe.Contains(task.StageDurations, StageName("test")) {
+		shouldBe.Equal(1, task.StageDurations["test"].Attempts)
+		shouldBe.Equal(1, task.StageDurations["test"].Failures)
+		shouldBe.GreaterOrEqual(task.StageDurations["test"].Duration, 50*time.Millisecond)
+	}
 	shouldBe.Equal(time.Duration(0), task.TotalDuration)
 	shouldBe.Equal(&expected, task.ErrorDetails)
 	shouldBe.Equal(StatusFailed, task.Status)
 }
+
+func TestStage_Execute_IllegalTransition(t *testing.T) {
+	shouldBe := assert.New(t)
+	mockedExecutor := &mockExecutor{}
+	s := &stage{
+		name:    BuildsStage,
+		success: StepBuildsDone,
+		failure: StepBuildsFailed,
+		execute: mockedExecutor.execute,
+	}
+	task := &Task{Request: Request{ID: "test-123"}, Status: StatusCompleted, LastStep: StepCompletionDone}
+	result := s.Execute(context.TODO(), task)
+	mockedExecutor.AssertNotCalled(t, "execute", mock.Anything, mock.Anything)
+	shouldBe.False(result)
+	shouldBe.Equal(StatusCompleted, task.Status)
+	shouldBe.Equal(StepCompletionDone, task.LastStep)
+	shouldBe.NotNil(task.ErrorDetails)
+}
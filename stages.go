@@ -3,6 +3,7 @@ package migration
 import (
 	"context"
 	"sort"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -18,21 +19,37 @@ type Stages interface {
 }
 
 type stages struct {
-	current int
-	task    *Task
-	stages  []*stage
-	updater func(ctx context.Context, task *Task) error
+	current          int
+	task             *Task
+	stages           []*stage
+	updater          func(ctx context.Context, task *Task) error
+	progressInterval time.Duration
 }
 
 // NewStages creates a new Stages instance which uses the given Updater to update the status of tasks.
 func NewStages(updater Updater, task *Task) Stages {
 	return &stages{
-		current: -1,
-		updater: updater,
-		task:    task,
+		current:          -1,
+		updater:          updater,
+		task:             task,
+		progressInterval: defaultProgressInterval,
 	}
 }
 
+// NewStagesWithRestart creates a new Stages instance like NewStages which honours Request.Restart, the task is reset
+// to before the restart stage using Task.ResetTo so all stages from that point are executed when they are Set.
+// Request.Restart is cleared once applied, so a task requeued later resumes instead of restarting again.
+func NewStagesWithRestart(updater Updater, task *Task) (Stages, error) {
+	if task.Restart != "" {
+		if err := task.ResetTo(task.Restart); err != nil {
+			return nil, err
+		}
+		log.Info().Str("module", "github.com/estafette/migration").Str("taskID", task.ID).Str("restart", string(task.Restart)).Str("lastStep", task.LastStep.String()).Msg("task restarted")
+		task.Restart = ""
+	}
+	return NewStages(updater, task), nil
+}
+
 // Current returns the current stage or nil if there is no current stage.
 func (ss *stages) Current() Stage {
 	if ss.current == -1 || ss.current >= len(ss.stages) {
@@ -42,19 +59,33 @@ func (ss *stages) Current() Stage {
 }
 
 // ExecuteNext executes the next stage, saves result to using Updater and returns the changes and if the stage failed.
+// Stage and task metrics are recorded if ctx carries Metrics, see ContextWithMetrics.
+// Executors can report progress of the stage using ProgressFrom, which is persisted using the Updater.
+// The Plan of a dry-run is reset when its first stage is executed.
 func (ss *stages) ExecuteNext(ctx context.Context) (result bool) {
 	defer func() {
 		result = ss.updateStatus(result)
 	}()
 	stg := ss.Next()
+	if ss.task.DryRun && ss.current == 0 {
+		// a dry-run doesn't move Task.LastStep, so a requeued dry-run plans all stages again
+		ss.task.Plan = &Plan{}
+	}
 	log.Info().Str("module", "github.com/estafette/migration").Str("taskID", ss.task.ID).Str("stage", string(stg.Name())).Msg("stage started")
 	start := ss.task.TotalDuration
-	result = stg.Execute(ctx, ss.task)
+	status := ss.task.Status
+	began := time.Now()
+	result = stg.Execute(contextWithProgress(ctx, newProgressReporter(stg.Name(), ss.task, ss.updater, ss.progressInterval)), ss.task)
+	metrics := MetricsFrom(ctx)
+	metrics.observeStage(stg.Name(), result, time.Since(began))
+	if ss.task.Status != status {
+		metrics.observeTask(ss.task.Status)
+	}
 	if !result {
-		log.Warn().Str("module", "github.com/estafette/migration").Str("taskID", ss.task.ID).Msg("task failed, stopping migration")
+		log.Warn().Str("module", "github.com/estafette/migration").Str("taskID", ss.task.ID).Dict("stageDurations", _stageDurationsDict(ss.task)).Msg("task failed, stopping migration")
 		return result
 	}
-	log.Info().Str("module", "github.com/estafette/migration").Dur("took", ss.task.TotalDuration-start).Str("taskID", ss.task.ID).Str("stage", string(stg.Name())).Msg("stage done")
+	log.Info().Str("module", "github.com/estafette/migration").Dur("took", ss.task.TotalDuration-start).Str("taskID", ss.task.ID).Str("stage", string(stg.Name())).Dict("stageDurations", _stageDurationsDict(ss.task)).Msg("stage done")
 	return result
 }
 
@@ -2,6 +2,7 @@ package migration
 
 import (
 	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
@@ -126,3 +127,34 @@ func TestStages_Skipped_Success(t *testing.T) {
 	mockedUpdater.AssertNumberOfCalls(t, "update", 4)
 	skippedExecutor.AssertNotCalled(t, "execute", mock.Anything, mock.Anything)
 }
+
+func TestNewStagesWithRestart(t *testing.T) {
+	shouldBe := assert.New(t)
+	mockedUpdater := &mockUpdater{}
+	mockedUpdater.On("update", mock.Anything, mock.Anything).Return(nil)
+	mockedExecutor := &mockExecutor{}
+	mockedExecutor.On("execute", mock.Anything, mock.Anything).Return(nil)
+	task := _WaitingTask()
+	task.Status = StatusInProgress
+	task.LastStep = StepComputedTablesDone
+	task.Restart = BuildLogsStage
+	ss, err := NewStagesWithRestart(mockedUpdater.update, task)
+	if !shouldBe.Nil(err) {
+		return
+	}
+	ss.Set(BuildsStage, mockedExecutor.execute).
+		Set(BuildLogsStage, mockedExecutor.execute).
+		Set(ComputedTablesStage, mockedExecutor.execute)
+	shouldBe.Equal(2, ss.Len())
+	shouldBe.Equal(StepBuildsDone, task.LastStep)
+	shouldBe.Equal(StageName(""), task.Restart, "restart is cleared once applied")
+	for ss.HasNext() {
+		shouldBe.True(ss.ExecuteNext(context.TODO()))
+	}
+	shouldBe.Equal(StepComputedTablesDone, task.LastStep)
+
+	task.Status = StatusCompleted
+	task.Restart = LastStage
+	_, err = NewStagesWithRestart(mockedUpdater.update, task)
+	shouldBe.True(errors.Is(err, ErrIllegalTransition))
+}
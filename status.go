@@ -4,9 +4,16 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strconv"
 )
 
-const StatusUnset Status = -1
+const (
+	StatusUnset Status = -1
+	// StatusUnknown is the result of StatusFrom for values not known by this version, like a status added by a newer server.
+	StatusUnknown Status = -2
+)
+
+var ErrUnknownStatus = fmt.Errorf("unknown migration task status")
 
 const (
 	StatusQueued Status = iota
@@ -22,21 +29,47 @@ func (s *Status) MarshalJSON() ([]byte, error) {
 	return json.Marshal(s.String())
 }
 
+// UnmarshalJSON returns ErrUnknownStatus for values not known by this version, the status is set to StatusUnknown.
 func (s *Status) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
 	var statusStr string
 	if err := json.Unmarshal(data, &statusStr); err != nil {
 		return err
 	}
 	*s = StatusFrom(statusStr)
+	if *s == StatusUnknown {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, statusStr)
+	}
 	return nil
 }
 
+// Scan status names written by SQLStore or integers written by Value, as int64, string or []byte depending on the
+// driver and column type. It returns ErrUnknownStatus for values not known by this version and the status is set to StatusUnknown.
 func (s *Status) Scan(src any) error {
-	if val, ok := src.(string); ok {
+	switch val := src.(type) {
+	case []byte:
+		return s.Scan(string(val))
+	case string:
+		if number, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return s.Scan(number)
+		}
 		*s = StatusFrom(val)
+		if *s == StatusUnknown {
+			return fmt.Errorf("%w: %q", ErrUnknownStatus, val)
+		}
 		return nil
+	case int64:
+		*s = Status(val)
+		if s.String() == "unknown" {
+			*s = StatusUnknown
+			return fmt.Errorf("%w: %d", ErrUnknownStatus, val)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", src, StatusUnknown)
 	}
-	return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", src, Status(-2))
 }
 
 func (s *Status) Value() (driver.Value, error) {
@@ -86,6 +119,6 @@ func StatusFrom(str string) Status {
 	case "":
 		return StatusUnset
 	default:
-		return -1
+		return StatusUnknown
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strconv"
 )
 
 // Predefined steps are created with space between them in case we need to add more steps in between without modifying this library.
@@ -26,6 +27,10 @@ const (
 	StepBuildVersionsDone       Step = 72
 	StepComputedTablesFailed    Step = 81
 	StepComputedTablesDone      Step = 82
+	StepVerificationFailed      Step = 83
+	StepVerificationDone        Step = 84
+	StepLogChecksumsFailed      Step = 85
+	StepLogChecksumsDone        Step = 86
 	StepArchiveFailed           Step = 89
 	StepArchiveDone             Step = 90
 	StepCallbackFailed          Step = 91
@@ -34,27 +39,58 @@ const (
 	StepCompletionDone          Step = 992
 )
 
+// StepUnknown is the result of StepFrom for values not known by this version, like a step added by a newer server.
+const StepUnknown Step = -1
+
+var ErrUnknownStep = fmt.Errorf("unknown migration task step")
+
 type Step int
 
 func (s *Step) MarshalJSON() ([]byte, error) {
 	return json.Marshal(s.String())
 }
 
+// UnmarshalJSON returns ErrUnknownStep for values not known by this version, the step is set to StepUnknown.
 func (s *Step) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
 	var stepStr string
 	if err := json.Unmarshal(data, &stepStr); err != nil {
 		return err
 	}
 	*s = StepFrom(stepStr)
+	if *s == StepUnknown {
+		return fmt.Errorf("%w: %q", ErrUnknownStep, stepStr)
+	}
 	return nil
 }
 
+// Scan step names written by SQLStore or integers written by Value, as int64, string or []byte depending on the
+// driver and column type. It returns ErrUnknownStep for values not known by this version and the step is set to StepUnknown.
 func (s *Step) Scan(src any) error {
-	if val, ok := src.(string); ok {
+	switch val := src.(type) {
+	case []byte:
+		return s.Scan(string(val))
+	case string:
+		if number, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return s.Scan(number)
+		}
 		*s = StepFrom(val)
+		if *s == StepUnknown {
+			return fmt.Errorf("%w: %q", ErrUnknownStep, val)
+		}
+		return nil
+	case int64:
+		*s = Step(val)
+		if s.String() == "unknown" {
+			*s = StepUnknown
+			return fmt.Errorf("%w: %d", ErrUnknownStep, val)
+		}
 		return nil
+	default:
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", src, StepUnknown)
 	}
-	return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %T", src, Step(-1))
 }
 
 func (s *Step) Value() (driver.Value, error) {
@@ -103,6 +139,14 @@ func (s *Step) String() string {
 		return "computed_tables_failed"
 	case StepComputedTablesDone:
 		return "computed_tables_done"
+	case StepVerificationFailed:
+		return "verification_failed"
+	case StepVerificationDone:
+		return "verification_done"
+	case StepLogChecksumsFailed:
+		return "log_checksums_failed"
+	case StepLogChecksumsDone:
+		return "log_checksums_done"
 	case StepArchiveFailed:
 		return "archive_failed"
 	case StepArchiveDone:
@@ -156,6 +200,14 @@ func StepFrom(str string) Step {
 		return StepComputedTablesFailed
 	case "computed_tables_done":
 		return StepComputedTablesDone
+	case "verification_failed":
+		return StepVerificationFailed
+	case "verification_done":
+		return StepVerificationDone
+	case "log_checksums_failed":
+		return StepLogChecksumsFailed
+	case "log_checksums_done":
+		return StepLogChecksumsDone
 	case "archive_failed":
 		return StepArchiveFailed
 	case "archive_done":
@@ -169,6 +221,6 @@ func StepFrom(str string) Step {
 	case "completion_done":
 		return StepCompletionDone
 	default:
-		return -1
+		return StepUnknown
 	}
 }
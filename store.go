@@ -0,0 +1,27 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+)
+
+var (
+	ErrTaskNotFound  = fmt.Errorf("migration task not found")
+	ErrTaskConflict  = fmt.Errorf("migration task was modified concurrently")
+	ErrNoQueuedTasks = fmt.Errorf("no queued migration tasks")
+)
+
+// Store persists migration tasks.
+type Store interface {
+	// Create a new task, if the ID of the task is not provided it is generated.
+	Create(ctx context.Context, task *Task) error
+	// Get task using task ID, returns ErrTaskNotFound if it does not exist.
+	Get(ctx context.Context, taskID string) (*Task, error)
+	// Update task if Task.Version matches the stored version, returns ErrTaskConflict otherwise.
+	// Update has the signature of Updater, so it can be passed to NewStages.
+	Update(ctx context.Context, task *Task) error
+	// List tasks with the given status ordered by Task.QueuedAt.
+	List(ctx context.Context, status Status) ([]*Task, error)
+	// ClaimNext queued task by moving it to StatusInProgress, returns ErrNoQueuedTasks if there is none.
+	ClaimNext(ctx context.Context) (*Task, error)
+}
@@ -3,13 +3,10 @@ package migration
 import (
 	"database/sql"
 	"fmt"
-	"regexp"
 	"sort"
 	"time"
 )
 
-var tld = regexp.MustCompile(`\.(com|org)`)
-
 type Request struct {
 	ID          string    `json:"id,omitempty"`
 	FromSource  string    `json:"fromSource"`
@@ -20,6 +17,9 @@ type Request struct {
 	ToName      string    `json:"toName"`
 	CallbackURL *string   `json:"callbackURL,omitempty"`
 	Restart     StageName `json:"restart,omitempty"`
+	// DryRun executes stages without writing, executors add the changes they would make to Task.Plan.
+	// A dry-run task completes with the Plan and never calls the callback.
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
 type Task struct {
@@ -32,40 +32,61 @@ type Task struct {
 	ErrorDetails  *string       `json:"errorDetails,omitempty"`
 	QueuedAt      time.Time     `json:"queuedAt,omitempty"`
 	UpdatedAt     time.Time     `json:"updatedAt,omitempty"`
+	// Version is incremented on every Store update and used for optimistic concurrency.
+	Version int64 `json:"version,omitempty"`
+	// Progress of the stage being executed.
+	Progress *Progress `json:"progress,omitempty"`
+	// History of stage attempts in order of execution.
+	History []HistoryEntry `json:"history,omitempty"`
+	// StageDurations of all attempts per stage, unlike TotalDuration it includes failed attempts.
+	StageDurations map[StageName]*StageDuration `json:"stageDurations,omitempty"`
+	// Plan of a dry-run, see Request.DryRun.
+	Plan *Plan `json:"plan,omitempty"`
+	// Checkpoints of stages which didn't finish, executors resume from them, see ProgressReporter.Checkpoint.
+	Checkpoints map[StageName]string `json:"checkpoints,omitempty"`
 }
 
-func (t *Task) FromFQN() string {
-	return fmt.Sprintf("%s/%s/%s", t.FromSource, t.FromOwner, t.FromName)
+// FromFQN is the fully qualified name of the source repository, aliases of known sources are replaced by their canonical host.
+func (r *Request) FromFQN() string {
+	return fmt.Sprintf("%s/%s/%s", DefaultSources.Canonical(r.FromSource), r.FromOwner, r.FromName)
 }
 
-func (t *Task) ToFQN() string {
-	return fmt.Sprintf("%s/%s/%s", t.ToSource, t.ToOwner, t.ToName)
+// ToFQN is the fully qualified name of the target repository, aliases of known sources are replaced by their canonical host.
+func (r *Request) ToFQN() string {
+	return fmt.Sprintf("%s/%s/%s", DefaultSources.Canonical(r.ToSource), r.ToOwner, r.ToName)
 }
 
+// SqlArgs of the task, use them as @name parameters with SQLDialect.Bind.
 func (t *Task) SqlArgs() []sql.NamedArg {
 	args := []sql.NamedArg{
 		sql.Named("updatedAt", t.UpdatedAt),
 		sql.Named("totalDuration", t.TotalDuration),
-		sql.Named("toSourceName", tld.ReplaceAllString(t.ToSource, "")),
+		sql.Named("toSourceName", DefaultSources.Name(t.ToSource)),
 		sql.Named("toSource", t.ToSource),
 		sql.Named("toOwner", t.ToOwner),
 		sql.Named("toName", t.ToName),
 		sql.Named("toFullName", t.ToOwner+"/"+t.ToName),
 		sql.Named("status", t.Status.String()),
+		sql.Named("stageDurations", jsonColumn{&t.StageDurations}),
 		sql.Named("releases", t.Releases),
 		sql.Named("queuedAt", t.QueuedAt),
+		sql.Named("progress", jsonColumn{&t.Progress}),
+		sql.Named("plan", jsonColumn{&t.Plan}),
 		sql.Named("lastStep", t.LastStep.String()),
 		sql.Named("id", t.ID),
-		sql.Named("fromSourceName", tld.ReplaceAllString(t.FromSource, "")),
+		sql.Named("history", jsonColumn{&t.History}),
+		sql.Named("fromSourceName", DefaultSources.Name(t.FromSource)),
 		sql.Named("fromSource", t.FromSource),
 		sql.Named("fromOwner", t.FromOwner),
 		sql.Named("fromName", t.FromName),
 		sql.Named("fromFullName", t.FromOwner+"/"+t.FromName),
 		sql.Named("errorDetails", t.ErrorDetails),
+		sql.Named("dryRun", t.DryRun),
+		sql.Named("checkpoints", jsonColumn{&t.Checkpoints}),
 		sql.Named("callbackURL", t.CallbackURL),
 		sql.Named("builds", t.Builds),
 	}
-	// !NOTICE! The order of the arguments is important, it helps in replacing arguments in query.
+	// Sorted for a stable order, bind them to a query by name using SQLDialect.Bind instead of relying on the order.
 	sort.Slice(args, func(i, j int) bool {
 		return args[i].Name > args[j].Name
 	})
@@ -2,6 +2,7 @@ package migration
 
 import (
 	"database/sql"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -33,27 +34,109 @@ func TestTask_SqlArgs(t *testing.T) {
 	}
 	args := task.SqlArgs()
 	shouldbe := assert.New(t)
-	shouldbe.Equal(20, len(args))
+	shouldbe.Equal(26, len(args))
 	shouldbe.Equal([]sql.NamedArg{
 		sql.Named("updatedAt", task.UpdatedAt),
 		sql.Named("totalDuration", task.TotalDuration),
-		sql.Named("toSourceName", tld.ReplaceAllString(task.ToSource, "")),
+		sql.Named("toSourceName", "gitlab"),
 		sql.Named("toSource", task.ToSource),
 		sql.Named("toOwner", task.ToOwner),
 		sql.Named("toName", task.ToName),
 		sql.Named("toFullName", task.ToOwner+"/"+task.ToName),
 		sql.Named("status", task.Status.String()),
+		sql.Named("stageDurations", jsonColumn{&task.StageDurations}),
 		sql.Named("releases", task.Releases),
 		sql.Named("queuedAt", task.QueuedAt),
+		sql.Named("progress", jsonColumn{&task.Progress}),
+		sql.Named("plan", jsonColumn{&task.Plan}),
 		sql.Named("lastStep", task.LastStep.String()),
 		sql.Named("id", task.ID),
-		sql.Named("fromSourceName", tld.ReplaceAllString(task.FromSource, "")),
+		sql.Named("history", jsonColumn{&task.History}),
+		sql.Named("fromSourceName", "github"),
 		sql.Named("fromSource", task.FromSource),
 		sql.Named("fromOwner", task.FromOwner),
 		sql.Named("fromName", task.FromName),
 		sql.Named("fromFullName", task.FromOwner+"/"+task.FromName),
 		sql.Named("errorDetails", task.ErrorDetails),
+		sql.Named("dryRun", task.DryRun),
+		sql.Named("checkpoints", jsonColumn{&task.Checkpoints}),
 		sql.Named("callbackURL", task.CallbackURL),
 		sql.Named("builds", task.Builds),
 	}, args)
 }
+
+func TestTask_recordStageDuration(t *testing.T) {
+	shouldBe := assert.New(t)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	task := &Task{}
+	task.recordStageDuration(BuildsStage, start, start.Add(time.Minute), true)
+	task.recordStageDuration(BuildsStage, start.Add(time.Hour), start.Add(time.Hour+2*time.Minute), false)
+	task.recordStageDuration(ReleasesStage, start, start.Add(time.Second), false)
+	shouldBe.Equal(map[StageName]*StageDuration{
+		BuildsStage:   {StartedAt: start.Add(time.Hour), FinishedAt: start.Add(time.Hour + 2*time.Minute), Duration: 3 * time.Minute, Attempts: 2, Failures: 1},
+		ReleasesStage: {StartedAt: start, FinishedAt: start.Add(time.Second), Duration: time.Second, Attempts: 1},
+	}, task.StageDurations)
+}
+
+func TestStatus_UnmarshalJSON(t *testing.T) {
+	shouldBe := assert.New(t)
+	var status Status
+	shouldBe.Nil(json.Unmarshal([]byte(`"in_progress"`), &status))
+	shouldBe.Equal(StatusInProgress, status)
+	shouldBe.Nil(json.Unmarshal([]byte(`""`), &status))
+	shouldBe.Equal(StatusUnset, status)
+	status = StatusFailed
+	shouldBe.Nil(json.Unmarshal([]byte(`null`), &status))
+	shouldBe.Equal(StatusFailed, status)
+	err := json.Unmarshal([]byte(`"paused"`), &status)
+	shouldBe.ErrorIs(err, ErrUnknownStatus)
+	shouldBe.ErrorContains(err, `"paused"`)
+	shouldBe.Equal(StatusUnknown, status)
+	shouldBe.NotEqual(StatusUnset, status)
+}
+
+func TestStatus_Scan(t *testing.T) {
+	shouldBe := assert.New(t)
+	var status Status
+	shouldBe.Nil(status.Scan("completed"))
+	shouldBe.Equal(StatusCompleted, status)
+	shouldBe.Nil(status.Scan(int64(StatusCanceled)))
+	shouldBe.Equal(StatusCanceled, status)
+	err := status.Scan(int64(42))
+	shouldBe.ErrorIs(err, ErrUnknownStatus)
+	shouldBe.ErrorContains(err, "42")
+	shouldBe.Equal(StatusUnknown, status)
+	shouldBe.ErrorIs(status.Scan("paused"), ErrUnknownStatus)
+	shouldBe.Equal(StatusUnknown, status)
+	shouldBe.ErrorContains(status.Scan(1.5), "unsupported Scan")
+}
+
+func TestStep_UnmarshalJSON(t *testing.T) {
+	shouldBe := assert.New(t)
+	var step Step
+	shouldBe.Nil(json.Unmarshal([]byte(`"builds_done"`), &step))
+	shouldBe.Equal(StepBuildsDone, step)
+	shouldBe.Nil(json.Unmarshal([]byte(`null`), &step))
+	shouldBe.Equal(StepBuildsDone, step)
+	err := json.Unmarshal([]byte(`"artifacts_done"`), &step)
+	shouldBe.ErrorIs(err, ErrUnknownStep)
+	shouldBe.ErrorContains(err, `"artifacts_done"`)
+	shouldBe.Equal(StepUnknown, step)
+	var task Task
+	shouldBe.ErrorIs(json.Unmarshal([]byte(`{"status":"queued","lastStep":"artifacts_done"}`), &task), ErrUnknownStep)
+}
+
+func TestStep_Scan(t *testing.T) {
+	shouldBe := assert.New(t)
+	var step Step
+	shouldBe.Nil(step.Scan("releases_failed"))
+	shouldBe.Equal(StepReleasesFailed, step)
+	shouldBe.Nil(step.Scan(int64(StepWaiting)))
+	shouldBe.Equal(StepWaiting, step)
+	err := step.Scan(int64(1000))
+	shouldBe.ErrorIs(err, ErrUnknownStep)
+	shouldBe.ErrorContains(err, "1000")
+	shouldBe.Equal(StepUnknown, step)
+	shouldBe.ErrorIs(step.Scan("artifacts_done"), ErrUnknownStep)
+	shouldBe.ErrorContains(step.Scan(true), "unsupported Scan")
+}
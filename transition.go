@@ -0,0 +1,106 @@
+package migration
+
+import (
+	"fmt"
+)
+
+var ErrIllegalTransition = fmt.Errorf("illegal migration task transition")
+
+// statusTransitions lists the statuses a task can move to from each status, completed tasks can't be moved anymore.
+var statusTransitions = map[Status][]Status{
+	StatusUnset:      {StatusQueued},
+	StatusQueued:     {StatusQueued, StatusInProgress, StatusCanceled},
+	StatusInProgress: {StatusInProgress, StatusQueued, StatusFailed, StatusCompleted, StatusCanceled},
+	StatusFailed:     {StatusFailed, StatusQueued, StatusInProgress},
+	StatusCompleted:  {StatusCompleted},
+	StatusCanceled:   {StatusCanceled, StatusQueued},
+}
+
+// CanTransition returns true if a task can move from status to the given status.
+func (s Status) CanTransition(to Status) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransition returns true if a task can move from step to the given step. Steps only move forward,
+// except from a done step to the failed step of the same stage, which happens when a started stage fails.
+func (s Step) CanTransition(to Step) bool {
+	if to >= s {
+		return true
+	}
+	return s.isDone() && to == s-1
+}
+
+// isDone returns true for the steps of a successfully executed stage, predefined failed steps precede their done step.
+func (s Step) isDone() bool {
+	switch s {
+	case StepReleasesDone, StepReleaseLogsDone, StepReleaseLogObjectsDone, StepBuildsDone, StepBuildLogsDone, StepBuildLogObjectsDone,
+		StepBuildVersionsDone, StepComputedTablesDone, StepVerificationDone, StepLogChecksumsDone, StepArchiveDone, StepCallbackDone, StepCompletionDone:
+		return true
+	default:
+		return false
+	}
+}
+
+// Transition moves the task to the given status and step, it returns ErrIllegalTransition and leaves the task
+// unchanged if the move is not allowed.
+func (t *Task) Transition(status Status, step Step) error {
+	if !t.Status.CanTransition(status) || !t.LastStep.CanTransition(step) {
+		return fmt.Errorf("%w: task %s from %s/%s to %s/%s", ErrIllegalTransition, t.ID, t.Status.String(), t.LastStep.String(), status.String(), step.String())
+	}
+	t.Status = status
+	t.LastStep = step
+	return nil
+}
+
+// ResetTo moves the task back to before the given stage, so the stage and all stages after it are executed again and
+// their checkpoints are removed. LastStage resumes the task where it stopped, it keeps Task.LastStep and the checkpoints
+// so a failed stage resumes mid-stage and stages which succeeded are not executed again.
+// Completed tasks can't be reset and ErrIllegalTransition is returned, the status of other tasks is unchanged.
+func (t *Task) ResetTo(stage StageName) error {
+	if t.Status == StatusCompleted {
+		return fmt.Errorf("%w: task %s is completed and can't be restarted from %s", ErrIllegalTransition, t.ID, stage)
+	}
+	if stage == LastStage {
+		return nil
+	}
+	index := -1
+	for i, name := range stageNames {
+		if name == stage {
+			index = i
+		}
+	}
+	if index == -1 {
+		return fmt.Errorf("%w: task %s can't be restarted from unknown stage %q", ErrIllegalTransition, t.ID, stage)
+	}
+	if t.LastStep >= stage.SuccessStep() {
+		t.LastStep = StepWaiting
+		if index > 0 {
+			t.LastStep = stageNames[index-1].SuccessStep()
+		}
+	}
+	for _, name := range stageNames[index:] {
+		delete(t.Checkpoints, name)
+	}
+	return nil
+}
+
+// ToMigration queues a completed dry-run again as a migration which writes the changes, Task.Plan is kept to compare
+// the projected changes with the migrated ones. ErrIllegalTransition is returned for other tasks, a dry-run which
+// isn't completed can be queued again as is.
+func (t *Task) ToMigration() error {
+	if !t.DryRun || t.Status != StatusCompleted {
+		return fmt.Errorf("%w: task %s is not a completed dry-run", ErrIllegalTransition, t.ID)
+	}
+	t.DryRun = false
+	t.Status = StatusQueued
+	t.LastStep = StepWaiting
+	t.Progress = nil
+	t.ErrorDetails = nil
+	t.Checkpoints = nil
+	return nil
+}
@@ -0,0 +1,87 @@
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask_Transition(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   Task
+		status Status
+		step   Step
+		legal  bool
+	}{
+		{name: "start_stage", from: Task{Status: StatusQueued, LastStep: StepWaiting}, status: StatusInProgress, step: StepReleasesDone, legal: true},
+		{name: "next_stage", from: Task{Status: StatusInProgress, LastStep: StepReleasesDone}, status: StatusInProgress, step: StepReleaseLogsDone, legal: true},
+		{name: "fail_stage", from: Task{Status: StatusInProgress, LastStep: StepBuildsDone}, status: StatusFailed, step: StepBuildsFailed, legal: true},
+		{name: "restart_failed", from: Task{Status: StatusFailed, LastStep: StepBuildsFailed}, status: StatusQueued, step: StepBuildsFailed, legal: true},
+		{name: "retry_failed_stage", from: Task{Status: StatusFailed, LastStep: StepBuildsFailed}, status: StatusInProgress, step: StepBuildsDone, legal: true},
+		{name: "complete", from: Task{Status: StatusInProgress, LastStep: StepCallbackDone}, status: StatusCompleted, step: StepCallbackDone, legal: true},
+		{name: "requeue_canceled", from: Task{Status: StatusCanceled, LastStep: StepBuildsDone}, status: StatusQueued, step: StepBuildsDone, legal: true},
+		{name: "requeue_completed", from: Task{Status: StatusCompleted, LastStep: StepCompletionDone}, status: StatusQueued, step: StepCompletionDone, legal: false},
+		{name: "complete_queued", from: Task{Status: StatusQueued, LastStep: StepWaiting}, status: StatusCompleted, step: StepWaiting, legal: false},
+		{name: "step_backwards", from: Task{Status: StatusInProgress, LastStep: StepBuildsDone}, status: StatusInProgress, step: StepReleasesDone, legal: false},
+		{name: "fail_other_stage", from: Task{Status: StatusInProgress, LastStep: StepBuildsDone}, status: StatusFailed, step: StepReleasesFailed, legal: false},
+		{name: "step_backwards_from_failed", from: Task{Status: StatusInProgress, LastStep: StepBuildsFailed}, status: StatusInProgress, step: StepReleasesDone, legal: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shouldBe := assert.New(t)
+			task := tt.from
+			err := task.Transition(tt.status, tt.step)
+			if tt.legal {
+				shouldBe.Nil(err)
+				shouldBe.Equal(tt.status, task.Status)
+				shouldBe.Equal(tt.step, task.LastStep)
+			} else {
+				shouldBe.True(errors.Is(err, ErrIllegalTransition))
+				shouldBe.Equal(tt.from, task)
+			}
+		})
+	}
+}
+
+func TestTask_ResetTo(t *testing.T) {
+	tests := []struct {
+		name        string
+		from        Task
+		restart     StageName
+		lastStep    Step
+		checkpoints map[StageName]string
+		legal       bool
+	}{
+		{name: "earlier_stage", from: Task{Status: StatusInProgress, LastStep: StepComputedTablesDone}, restart: BuildLogsStage, lastStep: StepBuildsDone, legal: true},
+		{name: "first_stage", from: Task{Status: StatusFailed, LastStep: StepBuildsFailed}, restart: ReleasesStage, lastStep: StepWaiting, legal: true},
+		{name: "later_stage", from: Task{Status: StatusQueued, LastStep: StepBuildsFailed}, restart: ArchiveStage, lastStep: StepBuildsFailed, legal: true},
+		{name: "last_stage_done", from: Task{Status: StatusQueued, LastStep: StepComputedTablesDone}, restart: LastStage, lastStep: StepComputedTablesDone, checkpoints: map[StageName]string{BuildsStage: "1", BuildLogsStage: "2"}, legal: true},
+		{name: "last_stage_failed", from: Task{Status: StatusFailed, LastStep: StepBuildsFailed}, restart: LastStage, lastStep: StepBuildsFailed, checkpoints: map[StageName]string{BuildsStage: "1", BuildLogsStage: "2"}, legal: true},
+		{name: "last_stage_waiting", from: Task{Status: StatusQueued, LastStep: StepWaiting}, restart: LastStage, lastStep: StepWaiting, legal: true},
+		{name: "completed", from: Task{Status: StatusCompleted, LastStep: StepCompletionDone}, restart: BuildsStage, lastStep: StepCompletionDone},
+		{name: "unknown_stage", from: Task{Status: StatusQueued, LastStep: StepBuildsDone}, restart: "unknown", lastStep: StepBuildsDone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shouldBe := assert.New(t)
+			task := tt.from
+			task.Checkpoints = map[StageName]string{BuildsStage: "1", BuildLogsStage: "2"}
+			err := task.ResetTo(tt.restart)
+			shouldBe.Equal(tt.lastStep, task.LastStep)
+			shouldBe.Equal(tt.from.Status, task.Status)
+			if tt.checkpoints != nil {
+				shouldBe.Equal(tt.checkpoints, task.Checkpoints)
+			}
+			if tt.legal {
+				shouldBe.Nil(err)
+			} else {
+				shouldBe.True(errors.Is(err, ErrIllegalTransition))
+			}
+		})
+	}
+	task := Task{Status: StatusInProgress, LastStep: StepComputedTablesDone, Checkpoints: map[StageName]string{BuildsStage: "1", BuildLogsStage: "2"}}
+	assert.Nil(t, task.ResetTo(BuildLogsStage))
+	assert.Equal(t, map[StageName]string{BuildsStage: "1"}, task.Checkpoints, "checkpoints from the restart stage are removed")
+}
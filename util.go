@@ -1,11 +1,14 @@
 package migration
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 
+	contracts "github.com/estafette/estafette-ci-contracts"
 	"github.com/rs/zerolog/log"
 )
 
@@ -17,6 +20,86 @@ func _urlJoin(URL string, path ...string) string {
 	}
 }
 
+// _apiPath joins the escaped segments to the api base path, every segment stays a single path segment even if it contains
+// '/', spaces or '%' like GitLab group/subgroup or Azure DevOps organization/project owners. The dot segments "." and ".."
+// are escaped as well, otherwise they are removed when the path is cleaned.
+func _apiPath(api string, segments ...string) string {
+	escaped := make([]string, len(segments))
+	for index, segment := range segments {
+		switch segment {
+		case ".", "..":
+			escaped[index] = strings.Repeat("%2E", len(segment))
+		default:
+			escaped[index] = url.PathEscape(segment)
+		}
+	}
+	return _urlJoin(api, escaped...)
+}
+
+// _withQuery appends the encoded query to the api path.
+func _withQuery(api string, query url.Values) string {
+	if len(query) == 0 {
+		return api
+	}
+	return api + "?" + query.Encode()
+}
+
+// _decodeSteps decodes the steps of a build or release log one at a time, other fields of the log are skipped.
+func _decodeSteps(decoder *json.Decoder, fn func(step *contracts.BuildLogStep) error) error {
+	return _decodeRawSteps(decoder, func(raw json.RawMessage) error {
+		step := &contracts.BuildLogStep{}
+		if err := json.Unmarshal(raw, step); err != nil {
+			return fmt.Errorf("error while decoding log step: %w", err)
+		}
+		return fn(step)
+	})
+}
+
+// _decodeRawSteps reads the JSON of the steps of a build or release log one at a time, other fields of the log are skipped.
+func _decodeRawSteps(decoder *json.Decoder, fn func(step json.RawMessage) error) error {
+	if token, err := decoder.Token(); err != nil {
+		return fmt.Errorf("error while decoding log: %w", err)
+	} else if token != json.Delim('{') {
+		return fmt.Errorf("error while decoding log: unexpected %v", token)
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("error while decoding log: %w", err)
+		}
+		if key != "steps" {
+			var skip json.RawMessage
+			if err = decoder.Decode(&skip); err != nil {
+				return fmt.Errorf("error while decoding log field %v: %w", key, err)
+			}
+			continue
+		}
+		token, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("error while decoding log steps: %w", err)
+		}
+		if token == nil {
+			continue
+		}
+		if token != json.Delim('[') {
+			return fmt.Errorf("error while decoding log steps: unexpected %v", token)
+		}
+		for decoder.More() {
+			var step json.RawMessage
+			if err = decoder.Decode(&step); err != nil {
+				return fmt.Errorf("error while decoding log step: %w", err)
+			}
+			if err = fn(step); err != nil {
+				return err
+			}
+		}
+		if _, err = decoder.Token(); err != nil {
+			return fmt.Errorf("error while decoding log steps: %w", err)
+		}
+	}
+	return nil
+}
+
 func _successful(res *http.Response) ([]byte, error) {
 	defer _close(res.Body)
 	body, err := io.ReadAll(res.Body)
@@ -0,0 +1,320 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	contracts "github.com/estafette/estafette-ci-contracts"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrVerificationFailed = fmt.Errorf("verification failed")
+
+const (
+	// maxReportedDifferences in the error of a failed verification, the VerificationReport contains all of them.
+	maxReportedDifferences = 10
+	buildKind              = "build"
+	releaseKind            = "release"
+)
+
+// Difference between the source and target pipeline.
+type Difference struct {
+	// Kind is build or release.
+	Kind string `json:"kind"`
+	// Key identifies the build by version or the release by name, action and version.
+	// Duplicate keys are suffixed with their position like #2, ordered by start time.
+	Key string `json:"key"`
+	// Field that differs, missing if only the source has the key, unexpected if only the target has it,
+	// count if the number of builds or releases differs and ledger if the target row of a recorded change doesn't exist.
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s %s %s: %q != %q", d.Kind, d.Key, d.Field, d.From, d.To)
+}
+
+// Count of builds or releases in the source and target pipeline.
+type Count struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// VerificationReport of the comparison of the source and target pipeline of a Request.
+type VerificationReport struct {
+	From        string       `json:"from"`
+	To          string       `json:"to"`
+	Builds      Count        `json:"builds"`
+	Releases    Count        `json:"releases"`
+	Differences []Difference `json:"differences,omitempty"`
+}
+
+// OK returns true if the target pipeline has the same builds and releases as the source pipeline.
+func (r *VerificationReport) OK() bool {
+	return len(r.Differences) == 0
+}
+
+// Err returns ErrVerificationFailed including the first differences or nil if the report is OK.
+func (r *VerificationReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	differences := make([]string, 0, maxReportedDifferences)
+	for index, difference := range r.Differences {
+		if index == maxReportedDifferences {
+			differences = append(differences, fmt.Sprintf("and %d more", len(r.Differences)-maxReportedDifferences))
+			break
+		}
+		differences = append(differences, difference.String())
+	}
+	return fmt.Errorf("%w: %s and %s have %d differences: %s", ErrVerificationFailed, r.From, r.To, len(r.Differences), strings.Join(differences, ", "))
+}
+
+// Verifier compares the builds and releases of the source and target pipeline of a Request using the Client.
+type Verifier struct {
+	client Client
+	ledger Ledger
+}
+
+// NewVerifier returns a Verifier using the pipeline APIs of the client.
+func NewVerifier(client Client) *Verifier {
+	return &Verifier{client: client}
+}
+
+// NewVerifierWithLedger returns a Verifier which also checks that the target rows recorded in the ledger for the task exist,
+// and pairs log objects using the ledger instead of build versions and release names.
+func NewVerifierWithLedger(client Client, ledger Ledger) *Verifier {
+	return &Verifier{client: client, ledger: ledger}
+}
+
+// Verify compares counts, versions, revisions, branches, statuses and release targets of the builds and the
+// statuses of the releases of the source and target pipeline. Differences are returned in the report, the error
+// is only set if the pipelines could not be retrieved.
+func (v *Verifier) Verify(ctx context.Context, request *Request) (*VerificationReport, error) {
+	report := &VerificationReport{From: request.FromFQN(), To: request.ToFQN()}
+	fromBuilds, err := v.builds(ctx, request.FromSource, request.FromOwner, request.FromName)
+	if err != nil {
+		return nil, fmt.Errorf("verifying builds of %s: %w", report.From, err)
+	}
+	toBuilds, err := v.builds(ctx, request.ToSource, request.ToOwner, request.ToName)
+	if err != nil {
+		return nil, fmt.Errorf("verifying builds of %s: %w", report.To, err)
+	}
+	fromReleases, err := v.releases(ctx, request.FromSource, request.FromOwner, request.FromName)
+	if err != nil {
+		return nil, fmt.Errorf("verifying releases of %s: %w", report.From, err)
+	}
+	toReleases, err := v.releases(ctx, request.ToSource, request.ToOwner, request.ToName)
+	if err != nil {
+		return nil, fmt.Errorf("verifying releases of %s: %w", report.To, err)
+	}
+	report.Builds = Count{From: len(fromBuilds), To: len(toBuilds)}
+	report.Releases = Count{From: len(fromReleases), To: len(toReleases)}
+	report.compare(buildKind, report.Builds, _buildFields(fromBuilds), _buildFields(toBuilds))
+	report.compare(releaseKind, report.Releases, _releaseFields(fromReleases), _releaseFields(toReleases))
+	toBuildIDs := make(map[string]bool, len(toBuilds))
+	for _, build := range toBuilds {
+		toBuildIDs[build.ID] = true
+	}
+	toReleaseIDs := make(map[string]bool, len(toReleases))
+	for _, release := range toReleases {
+		toReleaseIDs[release.ID] = true
+	}
+	if err = v.compareLedger(ctx, report, request.ID, buildKind, EntityBuild, toBuildIDs); err != nil {
+		return nil, err
+	}
+	if err = v.compareLedger(ctx, report, request.ID, releaseKind, EntityRelease, toReleaseIDs); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Executor verifies the pipelines of the task and fails if they differ, a dry-run is not verified as nothing is migrated.
+func (v *Verifier) Executor() Executor {
+	return func(ctx context.Context, task *Task) error {
+		if task.DryRun {
+			return nil
+		}
+		report, err := v.Verify(ctx, &task.Request)
+		if err != nil {
+			return err
+		}
+		log.Info().Str("module", "github.com/estafette/migration").Str("taskID", task.ID).Int("builds", report.Builds.To).Int("releases", report.Releases.To).Int("differences", len(report.Differences)).Msg("verified migration")
+		return report.Err()
+	}
+}
+
+// compareLedger adds a difference for every change in the ledger of which the target row doesn't exist.
+func (v *Verifier) compareLedger(ctx context.Context, report *VerificationReport, taskID, kind string, entity EntityType, toIDs map[string]bool) error {
+	if v.ledger == nil || taskID == "" {
+		return nil
+	}
+	changes, err := v.ledger.Changes(ctx, taskID, entity)
+	if err != nil {
+		return fmt.Errorf("verifying %s changes of task %s: %w", entity, taskID, err)
+	}
+	for _, change := range changes {
+		if !toIDs[strconv.FormatInt(change.ToID, 10)] {
+			report.Differences = append(report.Differences, Difference{Kind: kind, Key: strconv.FormatInt(change.FromID, 10), Field: "ledger", To: strconv.FormatInt(change.ToID, 10)})
+		}
+	}
+	return nil
+}
+
+// compare the fields of all keys of the source and target pipeline.
+func (r *VerificationReport) compare(kind string, count Count, from, to map[string]map[string]string) {
+	if count.From != count.To {
+		r.Differences = append(r.Differences, Difference{Kind: kind, Field: "count", From: fmt.Sprint(count.From), To: fmt.Sprint(count.To)})
+	}
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fromFields, inFrom := from[key]
+		toFields, inTo := to[key]
+		switch {
+		case !inTo:
+			r.Differences = append(r.Differences, Difference{Kind: kind, Key: key, Field: "missing"})
+		case !inFrom:
+			r.Differences = append(r.Differences, Difference{Kind: kind, Key: key, Field: "unexpected"})
+		default:
+			fields := make([]string, 0, len(fromFields))
+			for field := range fromFields {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			for _, field := range fields {
+				if fromFields[field] != toFields[field] {
+					r.Differences = append(r.Differences, Difference{Kind: kind, Key: key, Field: field, From: fromFields[field], To: toFields[field]})
+				}
+			}
+		}
+	}
+}
+
+// builds returns all builds of the pipeline, progress is reported per page.
+func (v *Verifier) builds(ctx context.Context, source, owner, name string) ([]*contracts.Build, error) {
+	builds := make([]*contracts.Build, 0)
+	filter := PipelineFilter{}
+	for filter.Page = 1; ; filter.Page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := v.client.ListPipelineBuilds(source, owner, name, filter)
+		if err != nil {
+			return nil, err
+		}
+		builds = append(builds, page.Items...)
+		ProgressFrom(ctx).Add(ctx, int64(len(page.Items)), fmt.Sprintf("%s/%s/%s builds page %d", source, owner, name, filter.Page))
+		if filter.Page >= page.Pagination.TotalPages {
+			return builds, nil
+		}
+	}
+}
+
+// releases returns all releases of the pipeline, progress is reported per page.
+func (v *Verifier) releases(ctx context.Context, source, owner, name string) ([]*contracts.Release, error) {
+	releases := make([]*contracts.Release, 0)
+	filter := PipelineFilter{}
+	for filter.Page = 1; ; filter.Page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := v.client.ListPipelineReleases(source, owner, name, filter)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, page.Items...)
+		ProgressFrom(ctx).Add(ctx, int64(len(page.Items)), fmt.Sprintf("%s/%s/%s releases page %d", source, owner, name, filter.Page))
+		if filter.Page >= page.Pagination.TotalPages {
+			return releases, nil
+		}
+	}
+}
+
+// _buildFields compared per build version.
+func _buildFields(builds []*contracts.Build) map[string]map[string]string {
+	fields := make(map[string]map[string]string, len(builds))
+	for index, key := range _buildKeys(builds) {
+		build := builds[index]
+		targets := make([]string, 0, len(build.ReleaseTargets))
+		for _, target := range build.ReleaseTargets {
+			actions := make([]string, 0, len(target.Actions))
+			for _, action := range target.Actions {
+				actions = append(actions, action.Name)
+			}
+			sort.Strings(actions)
+			targets = append(targets, target.Name+"("+strings.Join(actions, ",")+")")
+		}
+		sort.Strings(targets)
+		fields[key] = map[string]string{
+			"revision":       build.RepoRevision,
+			"branch":         build.RepoBranch,
+			"status":         string(build.BuildStatus),
+			"releaseTargets": strings.Join(targets, ","),
+		}
+	}
+	return fields
+}
+
+// _releaseFields compared per release name, action and version.
+func _releaseFields(releases []*contracts.Release) map[string]map[string]string {
+	fields := make(map[string]map[string]string, len(releases))
+	for index, key := range _releaseKeys(releases) {
+		fields[key] = map[string]string{
+			"status": string(releases[index].ReleaseStatus),
+		}
+	}
+	return fields
+}
+
+// _buildKeys sorts the builds by start time and returns the key of each build, which is its version.
+func _buildKeys(builds []*contracts.Build) []string {
+	sort.SliceStable(builds, func(i, j int) bool {
+		return _buildTime(builds[i]).Before(_buildTime(builds[j]))
+	})
+	keys := make([]string, len(builds))
+	seen := make(map[string]bool, len(builds))
+	for index, build := range builds {
+		keys[index] = _uniqueKey(seen, build.BuildVersion)
+	}
+	return keys
+}
+
+// _releaseKeys sorts the releases by start time and returns the key of each release, which is its name, action and version.
+func _releaseKeys(releases []*contracts.Release) []string {
+	sort.SliceStable(releases, func(i, j int) bool {
+		return _releaseTime(releases[i]).Before(_releaseTime(releases[j]))
+	})
+	keys := make([]string, len(releases))
+	seen := make(map[string]bool, len(releases))
+	for index, release := range releases {
+		key := release.Name + "/" + release.ReleaseVersion
+		if release.Action != "" {
+			key = release.Name + "/" + release.Action + "/" + release.ReleaseVersion
+		}
+		keys[index] = _uniqueKey(seen, key)
+	}
+	return keys
+}
+
+// _uniqueKey suffixes duplicate keys with their position, like version#2 for the second build of the version.
+func _uniqueKey(seen map[string]bool, key string) string {
+	unique := key
+	for position := 2; seen[unique]; position++ {
+		unique = fmt.Sprintf("%s#%d", key, position)
+	}
+	seen[unique] = true
+	return unique
+}
@@ -0,0 +1,120 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	contracts "github.com/estafette/estafette-ci-contracts"
+	manifest "github.com/estafette/estafette-ci-manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+// pipelineClient serves builds and releases per source from memory, pages hold a single item.
+type pipelineClient struct {
+	Client
+	builds   map[string][]*contracts.Build
+	releases map[string][]*contracts.Release
+}
+
+func (c *pipelineClient) ListPipelineBuilds(source, _, _ string, filter PipelineFilter) (*PagedBuildsResponse, error) {
+	builds := c.builds[source]
+	if len(builds) == 0 {
+		return &PagedBuildsResponse{}, nil
+	}
+	return &PagedBuildsResponse{Items: builds[filter.Page-1 : filter.Page], Pagination: contracts.Pagination{Page: filter.Page, Size: 1, TotalPages: len(builds), TotalItems: len(builds)}}, nil
+}
+
+func (c *pipelineClient) ListPipelineReleases(source, _, _ string, filter PipelineFilter) (*PagedReleasesResponse, error) {
+	releases := c.releases[source]
+	if len(releases) == 0 {
+		return &PagedReleasesResponse{}, nil
+	}
+	return &PagedReleasesResponse{Items: releases[filter.Page-1 : filter.Page], Pagination: contracts.Pagination{Page: filter.Page, Size: 1, TotalPages: len(releases), TotalItems: len(releases)}}, nil
+}
+
+func _verifierBuild(version, revision string, status contracts.Status, started time.Time, targets ...string) *contracts.Build {
+	build := &contracts.Build{BuildVersion: version, RepoRevision: revision, RepoBranch: "main", BuildStatus: status, StartedAt: &started}
+	for _, target := range targets {
+		build.ReleaseTargets = append(build.ReleaseTargets, contracts.ReleaseTarget{Name: target, Actions: []manifest.EstafetteReleaseAction{{Name: "deploy"}}})
+	}
+	return build
+}
+
+func _verifierRelease(name, version string, status contracts.Status, started time.Time) *contracts.Release {
+	return &contracts.Release{Name: name, ReleaseVersion: version, ReleaseStatus: status, StartedAt: &started}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	request := &Request{FromSource: "bitbucket.org", FromOwner: "owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "repo1"}
+	t.Run("equal", func(t *testing.T) {
+		shouldBe := assert.New(t)
+		client := &pipelineClient{
+			builds: map[string][]*contracts.Build{
+				"bitbucket.org": {_verifierBuild("1.0.0", "abc", contracts.StatusSucceeded, day, "production"), _verifierBuild("1.0.1", "def", contracts.StatusFailed, day.Add(time.Hour))},
+				"github.com":    {_verifierBuild("1.0.1", "def", contracts.StatusFailed, day.Add(time.Hour)), _verifierBuild("1.0.0", "abc", contracts.StatusSucceeded, day, "production")},
+			},
+			releases: map[string][]*contracts.Release{
+				"bitbucket.org": {_verifierRelease("production", "1.0.0", contracts.StatusSucceeded, day), _verifierRelease("production", "1.0.0", contracts.StatusFailed, day.Add(time.Hour))},
+				"github.com":    {_verifierRelease("production", "1.0.0", contracts.StatusSucceeded, day), _verifierRelease("production", "1.0.0", contracts.StatusFailed, day.Add(time.Hour))},
+			},
+		}
+		report, err := NewVerifier(client).Verify(context.Background(), request)
+		if shouldBe.Nil(err) {
+			shouldBe.True(report.OK())
+			shouldBe.Nil(report.Err())
+			shouldBe.Equal(Count{From: 2, To: 2}, report.Builds)
+			shouldBe.Equal(Count{From: 2, To: 2}, report.Releases)
+			shouldBe.Equal("bitbucket.org/owner1/repo1", report.From)
+		}
+	})
+	t.Run("differences", func(t *testing.T) {
+		shouldBe := assert.New(t)
+		client := &pipelineClient{
+			builds: map[string][]*contracts.Build{
+				"bitbucket.org": {_verifierBuild("1.0.0", "abc", contracts.StatusSucceeded, day, "production"), _verifierBuild("1.0.1", "def", contracts.StatusFailed, day.Add(time.Hour))},
+				"github.com":    {_verifierBuild("1.0.0", "abd", contracts.StatusSucceeded, day, "staging")},
+			},
+			releases: map[string][]*contracts.Release{
+				"bitbucket.org": {_verifierRelease("production", "1.0.0", contracts.StatusSucceeded, day), _verifierRelease("production", "1.0.0", contracts.StatusFailed, day.Add(time.Hour))},
+				"github.com":    {_verifierRelease("production", "1.0.0", contracts.StatusSucceeded, day), _verifierRelease("production", "1.0.0", contracts.StatusSucceeded, day.Add(time.Hour))},
+			},
+		}
+		report, err := NewVerifier(client).Verify(context.Background(), request)
+		if shouldBe.Nil(err) {
+			shouldBe.False(report.OK())
+			shouldBe.Equal([]Difference{
+				{Kind: "build", Field: "count", From: "2", To: "1"},
+				{Kind: "build", Key: "1.0.0", Field: "releaseTargets", From: "production(deploy)", To: "staging(deploy)"},
+				{Kind: "build", Key: "1.0.0", Field: "revision", From: "abc", To: "abd"},
+				{Kind: "build", Key: "1.0.1", Field: "missing"},
+				{Kind: "release", Key: "production/1.0.0#2", Field: "status", From: "failed", To: "succeeded"},
+			}, report.Differences)
+			shouldBe.True(errors.Is(report.Err(), ErrVerificationFailed))
+		}
+		task := &Task{Request: *request}
+		shouldBe.True(errors.Is(NewVerifier(client).Executor()(context.Background(), task), ErrVerificationFailed))
+		task.DryRun = true
+		shouldBe.Nil(NewVerifier(client).Executor()(context.Background(), task))
+	})
+}
+
+func TestVerifier_Verify_Ledger(t *testing.T) {
+	shouldBe := assert.New(t)
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	request := &Request{ID: "task-1", FromSource: "bitbucket.org", FromOwner: "owner1", FromName: "repo1", ToSource: "github.com", ToOwner: "owner1", ToName: "repo1"}
+	build := func(id string) *contracts.Build {
+		b := _verifierBuild("1.0.0", "abc", contracts.StatusSucceeded, day)
+		b.ID = id
+		return b
+	}
+	client := &pipelineClient{builds: map[string][]*contracts.Build{"bitbucket.org": {build("1")}, "github.com": {build("11")}}}
+	ledger := NewMemoryLedger()
+	shouldBe.Nil(ledger.Record(context.TODO(), "task-1", EntityBuild, Change{FromID: 1, ToID: 11}, Change{FromID: 2, ToID: 12}))
+	report, err := NewVerifierWithLedger(client, ledger).Verify(context.TODO(), request)
+	if shouldBe.Nil(err) {
+		shouldBe.Equal([]Difference{{Kind: "build", Key: "2", Field: "ledger", To: "12"}}, report.Differences)
+	}
+}